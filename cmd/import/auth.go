@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"habitsSync/internal/infrastructure/auth"
+)
+
+// loginFlags choose how to authorize
+func loginFlags(fs *flag.FlagSet, a *args) {
+	fs.BoolVar(&a.headless, "headless", false, "authorize pasting the address Google redirects to, for machines without a browser")
+}
+
+func authCommand(argv []string) {
+	a := args{}
+	fs := newFlagSet("auth")
+	accountFlags(fs, &a)
+	loginFlags(fs, &a)
+	fs.BoolVar(&a.create, "create", false, "allow sync -create to create spreadsheets too")
+	parse(fs, argv)
+	failOnErr(parsePaths(&a))
+
+	ctx, cancel := interruptible(a.timeout)
+	defer cancel()
+	authorize(ctx, a)
+}
+
+func authStatusCommand(argv []string) {
+	a := args{}
+	fs := newFlagSet("auth status")
+	accountFlags(fs, &a)
+	parse(fs, argv)
+	failOnErr(parsePaths(&a))
+
+	ctx, cancel := interruptible(a.timeout)
+	defer cancel()
+	authStatus(ctx, a)
+}
+
+func authRevokeCommand(argv []string) {
+	a := args{}
+	fs := newFlagSet("auth revoke")
+	accountFlags(fs, &a)
+	parse(fs, argv)
+	failOnErr(parsePaths(&a))
+
+	ctx, cancel := interruptible(a.timeout)
+	defer cancel()
+	authRevoke(ctx, a)
+}
+
+func authService(arg args) *auth.Service {
+	return auth.NewService(
+		auth.NewReadWriter(),
+		auth.NewAuthRepository(arg.credentialsPath, arg.tokenPath, features(arg)...),
+	)
+}
+
+func authorize(ctx context.Context, arg args) {
+	err := authService(arg).Handle(ctx, auth.AuthorizeCMD{Headless: arg.headless})
+	failOnErr(err)
+}
+
+func authStatus(ctx context.Context, arg args) {
+	failOnErr(authService(arg).Status(ctx))
+}
+
+func authRevoke(ctx context.Context, arg args) {
+	failOnErr(authService(arg).Revoke(ctx))
+}
@@ -1,31 +1,71 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
+	"fmt"
 	"habitsSync/internal/application"
 	"habitsSync/internal/domain"
 	"habitsSync/internal/infrastructure/auth"
 	"habitsSync/internal/infrastructure/drive"
 	"habitsSync/internal/infrastructure/sheets"
 	time2 "habitsSync/internal/infrastructure/time"
+	"io/ioutil"
 	"log"
 	"os"
+	"strings"
 	"time"
 )
 
 type args struct {
 	credentialsPath string
 	tokenPath       string
+	profile         string
+	subject         string
 	tmpPath         string
 	prefix          string
 	authorize       bool
+	authStatus      bool
+	authRevoke      bool
+	headless        bool
 	fromStr         string
 	toStr           string
 	quarter         int
 	sheetName       string
 	spreadsheet     string
+	compareStr      string
+	layoutStr       string
+	modeStr         string
+	journal         bool
+	journalSheet    string
+	periodsStr      string
+	year            int
+	sheetTemplate   string
+	charts          bool
+	conditional     bool
+	namedRanges     bool
+	rangePrefix     string
+	template        domain.Template
+	create          bool
+	folder          string
+	dryRun          bool
+	habitsStr       string
+	formatStr       string
+	output          string
+	targetsPath     string
+	parallelism     int
+	timeout         time.Duration
+	targets         []application.Target
+	periods         []application.Period
 	from            time.Time
 	to              time.Time
+	compare         application.Comparison
+	layout          application.Layout
+	mode            domain.WriteMode
+	// set are the names of the flags given on the command line
+	set map[string]bool
 }
 
 func parseDates(a *args) error {
@@ -44,38 +84,251 @@ func parseDates(a *args) error {
 	return nil
 }
 
-func parseArgs() (a args) {
-	flag.StringVar(&a.credentialsPath, "credentials", "credentials.json", "credentials file")
-	flag.StringVar(&a.tokenPath, "token", "auth.json", "token file")
-	flag.StringVar(&a.prefix, "prefix", "Loop Habits Backup", "prefix of the backup name")
-	flag.StringVar(&a.tmpPath, "tmp", "/tmp", "temporary directory where to store the DB")
-	flag.StringVar(&a.fromStr, "from", "", "yyyy-mm-dd date from where start importing Habits records")
-	flag.StringVar(&a.toStr, "to", "", "yyy-mm-dd date from where stop importing Habits records")
-	flag.StringVar(&a.spreadsheet, "spreadsheet", "", "name of the spreadsheet to import")
-	flag.StringVar(&a.sheetName, "sheet-name", "Import", "the name of the Sheet where data is going to be imported")
-	flag.BoolVar(&a.authorize, "auth", false, "authorize")
-	flag.IntVar(&a.quarter, "quarter", 0, "date range for the quarter of the current year")
-	flag.Parse()
+// targetFile is an entry of the file of targets
+type targetFile struct {
+	Spreadsheet string   `json:"spreadsheet"`
+	Sheet       string   `json:"sheet"`
+	Layout      string   `json:"layout"`
+	Habits      []string `json:"habits"`
+}
 
-	failOnErr(parseDates(&a))
+func parseTargets(a *args) error {
+	if a.targetsPath == "" {
+		return nil
+	}
+	if a.periodsStr != "" {
+		return errors.New("targets cannot be combined with periods")
+	}
 
-	return a
+	b, err := ioutil.ReadFile(a.targetsPath)
+	if err != nil {
+		return err
+	}
+	var entries []targetFile
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return fmt.Errorf("invalid targets file %v: %w", a.targetsPath, err)
+	}
+
+	for _, e := range entries {
+		var layout application.Layout
+		if e.Layout != "" {
+			if layout, err = application.ParseLayout(e.Layout); err != nil {
+				return err
+			}
+		}
+		a.targets = append(a.targets, application.Target{
+			Spreadsheet: e.Spreadsheet,
+			SheetName:   e.Sheet,
+			Layout:      layout,
+			Habits:      e.Habits,
+		})
+	}
+	return nil
 }
 
-func authorize(arg args) {
-	service := auth.NewService(
-		auth.NewReadWriter(),
-		auth.NewAuthRepository(arg.credentialsPath, arg.tokenPath),
-	)
-	err := service.Handle()
-	failOnErr(err)
+// parseHabits splits the comma separated names of the habits
+func parseHabits(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+func parsePeriods(a *args) (err error) {
+	if a.periodsStr == "" {
+		if a.set["year"] || a.set["sheet-template"] {
+			return errors.New("year and sheet-template can only be used with periods")
+		}
+		return nil
+	}
+	if a.set["sheet-name"] {
+		return errors.New("sheet-name cannot be combined with periods, use sheet-template instead")
+	}
+	if a.set["quarter"] || a.set["from"] || a.set["to"] {
+		return errors.New("quarter, from and to cannot be combined with periods, use year instead")
+	}
+	s := application.NewDatesService(time2.NewRepository())
+	a.periods, err = s.Periods(application.PeriodsCMD{
+		Every:    a.periodsStr,
+		Year:     a.year,
+		Template: a.sheetTemplate,
+	})
+	return err
+}
+
+// parsePaths defaults the credentials and the token to the config directory,
+// with the token of the profile
+func parsePaths(a *args) (err error) {
+	if a.credentialsPath == "" {
+		if a.credentialsPath, err = auth.DefaultPath("credentials.json"); err != nil {
+			return err
+		}
+	}
+	if a.tokenPath == "" {
+		if a.tokenPath, err = auth.DefaultPath("auth.json"); err != nil {
+			return err
+		}
+	}
+	a.tokenPath, err = auth.ProfileTokenPath(a.tokenPath, a.profile)
+	return err
+}
+
+// accountFlags are the flags of the commands reaching Google
+func accountFlags(fs *flag.FlagSet, a *args) {
+	fs.StringVar(&a.credentialsPath, "credentials", "", "credentials file, by default credentials.json in $XDG_CONFIG_HOME/hsync")
+	fs.StringVar(&a.tokenPath, "token", "", "token file, by default auth.json in $XDG_CONFIG_HOME/hsync. Encrypted with the passphrase of "+auth.PassphraseEnv+" when set")
+	fs.StringVar(&a.profile, "profile", "", "name of the profile, to keep the token of each Google account apart, e.g. work uses auth.work.json")
+	fs.DurationVar(&a.timeout, "timeout", 0, "stop after this long, e.g. 5m. No limit by default")
+}
+
+// driveFlags are the flags of the commands looking for the backups
+func driveFlags(fs *flag.FlagSet, a *args) {
+	fs.StringVar(&a.subject, "subject", "", "user impersonated by the service account of the credentials, with domain-wide delegation")
+	fs.StringVar(&a.prefix, "prefix", "Loop Habits Backup", "prefix of the backup name")
+}
+
+// backupFlags are the flags of the commands reading the habits of the backup
+func backupFlags(fs *flag.FlagSet, a *args) {
+	fs.StringVar(&a.tmpPath, "tmp", "/tmp", "temporary directory where to store the DB")
+	fs.StringVar(&a.fromStr, "from", "", "yyyy-mm-dd date from where start importing Habits records")
+	fs.StringVar(&a.toStr, "to", "", "yyy-mm-dd date from where stop importing Habits records")
+	fs.IntVar(&a.quarter, "quarter", 0, "date range for the quarter of the current year")
+}
+
+// reportFlags are the flags of the commands building the report
+func reportFlags(fs *flag.FlagSet, a *args) {
+	fs.StringVar(&a.layoutStr, "layout", "habits", "report: habits or weekdays")
+	fs.StringVar(&a.compareStr, "compare", "", "add delta and change columns against the previous period (previous) or the same period last year (last-year)")
+	fs.StringVar(&a.habitsStr, "habits", "", "comma separated names of the habits to import, by default all of them")
+}
+
+// syncFlags are the flags of the sync command only
+func syncFlags(fs *flag.FlagSet, a *args) {
+	fs.StringVar(&a.spreadsheet, "spreadsheet", "", "name of the spreadsheet to import")
+	fs.StringVar(&a.sheetName, "sheet-name", "Import", "the name of the Sheet where data is going to be imported")
+	fs.StringVar(&a.modeStr, "mode", "overwrite", "overwrite the Sheet, merge the habits into it keeping any other column, or append them as history")
+	fs.BoolVar(&a.journal, "journal", false, "import the notes of the repetitions too")
+	fs.StringVar(&a.journalSheet, "journal-sheet-name", "Journal", "the name of the Sheet where notes are going to be imported")
+	fs.StringVar(&a.periodsStr, "periods", "", "import every quarter or month of the year into its own Sheet")
+	fs.IntVar(&a.year, "year", 0, "year of the periods, by default the current year")
+	fs.StringVar(&a.sheetTemplate, "sheet-template", "", "name of the Sheet of each period, e.g. \"Q{q} {yyyy}\" or \"{month} {yyyy}\"")
+	fs.BoolVar(&a.charts, "charts", true, "add a chart of the report next to it")
+	fs.BoolVar(&a.conditional, "conditional-format", true, "highlight rates and changes with colors")
+	fs.BoolVar(&a.namedRanges, "named-ranges", false, "keep named ranges like habits_counts pointing to the imported data")
+	fs.StringVar(&a.rangePrefix, "named-ranges-prefix", "", "prefix of the named ranges, e.g. \"okr_\"")
+	fs.StringVar(&a.template.SheetName, "template", "", "name of the Sheet copied to create the Sheets that do not exist yet")
+	fs.StringVar(&a.template.Spreadsheet, "template-spreadsheet", "", "spreadsheet of the template, by default the one being imported")
+	fs.BoolVar(&a.create, "create", false, "create the spreadsheet if it does not exist. Authorize with hsync auth -create first")
+	fs.StringVar(&a.folder, "folder", "", "Drive folder where the spreadsheet is created")
+	fs.BoolVar(&a.dryRun, "dry-run", false, "print what would change on each Sheet without writing anything")
+	fs.StringVar(&a.targetsPath, "targets", "", "JSON file with the spreadsheets and Sheets to import into, see README")
+	fs.IntVar(&a.parallelism, "parallelism", application.DefaultParallelism, "number of spreadsheets of the targets updated at the same time")
+}
+
+func parseReport(a *args) (err error) {
+	if err = parseDates(a); err != nil {
+		return err
+	}
+	if a.compare, err = application.ParseComparison(a.compareStr); err != nil {
+		return err
+	}
+	a.layout, err = application.ParseLayout(a.layoutStr)
+	return err
+}
+
+func parseSync(a *args) (err error) {
+	if err = parseReport(a); err != nil {
+		return err
+	}
+	if err = parsePeriods(a); err != nil {
+		return err
+	}
+	if err = parseTargets(a); err != nil {
+		return err
+	}
+	if a.mode, err = application.ParseWriteMode(a.modeStr); err != nil {
+		return err
+	}
+	if !a.journal {
+		a.journalSheet = ""
+	}
+	return nil
 }
 
-func importData(arg args) {
-	r, err := drive.NewRepository(arg.credentialsPath, arg.tokenPath)
+func syncCommand(argv []string) {
+	a := args{}
+	fs := newFlagSet("sync")
+	accountFlags(fs, &a)
+	driveFlags(fs, &a)
+	backupFlags(fs, &a)
+	reportFlags(fs, &a)
+	syncFlags(fs, &a)
+	parse(fs, argv)
+	a.set = setFlags(fs)
+	failOnErr(parsePaths(&a))
+	failOnErr(parseSync(&a))
+
+	ctx, cancel := interruptible(a.timeout)
+	defer cancel()
+	importData(ctx, a)
+}
+
+// legacyCommand runs the flags given without a command, as they were before
+// the commands: a sync, or an authorization with -auth
+func legacyCommand(argv []string) {
+	a := args{}
+	fs := legacyFlags(&a)
+	parse(fs, argv)
+	a.set = setFlags(fs)
+	failOnErr(parsePaths(&a))
+	failOnErr(parseSync(&a))
+	log.Print("running hsync without a command is deprecated, use hsync sync or hsync auth")
+
+	ctx, cancel := interruptible(a.timeout)
+	defer cancel()
+	switch {
+	case a.authorize:
+		authorize(ctx, a)
+	case a.authStatus:
+		authStatus(ctx, a)
+	case a.authRevoke:
+		authRevoke(ctx, a)
+	default:
+		importData(ctx, a)
+	}
+}
+
+// legacyFlags are the flags of every command, as there were no commands
+func legacyFlags(a *args) *flag.FlagSet {
+	fs := flag.NewFlagSet("hsync", flag.ExitOnError)
+	accountFlags(fs, a)
+	driveFlags(fs, a)
+	backupFlags(fs, a)
+	reportFlags(fs, a)
+	syncFlags(fs, a)
+	fs.BoolVar(&a.authorize, "auth", false, "authorize, use hsync auth instead")
+	fs.BoolVar(&a.authStatus, "auth-status", false, "show the authorization, use hsync auth status instead")
+	fs.BoolVar(&a.authRevoke, "auth-revoke", false, "revoke the authorization, use hsync auth revoke instead")
+	loginFlags(fs, a)
+	return fs
+}
+
+// features are the ones the token must be authorized for
+func features(arg args) []auth.Feature {
+	features := append([]auth.Feature{}, auth.DefaultFeatures...)
+	if arg.create {
+		features = append(features, auth.CreateSpreadsheets)
+	}
+	return features
+}
+
+func importData(ctx context.Context, arg args) {
+	credentials := auth.Credentials{Path: arg.credentialsPath, TokenPath: arg.tokenPath, Subject: arg.subject}
+	provider := auth.NewProvider(credentials, features(arg)...)
+	r, err := drive.NewRepository(ctx, provider)
 	failOnErr(err)
 
-	s, err := sheets.NewRepository(arg.credentialsPath, arg.tokenPath)
+	s, err := sheets.NewRepository(ctx, provider)
 	failOnErr(err)
 
 	srv := application.NewSyncService(
@@ -85,15 +338,61 @@ func importData(arg args) {
 			r,
 		),
 		domain.NewSpreadsheet(r, s),
+		time2.NewRepository(),
 		os.Stdout)
 
-	err = srv.Handle(application.SyncCMD{
-		Prefix:      arg.prefix,
-		From:        arg.from,
-		To:          arg.to,
-		SheetName:   arg.sheetName,
-		Spreadsheet: arg.spreadsheet,
-	})
+	if len(arg.periods) != 0 {
+		err = srv.HandlePeriods(ctx, application.SyncPeriodsCMD{
+			Prefix:                arg.prefix,
+			Spreadsheet:           arg.spreadsheet,
+			Periods:               arg.periods,
+			Layout:                arg.layout,
+			Compare:               arg.compare,
+			Mode:                  arg.mode,
+			JournalSheetName:      arg.journalSheet,
+			Charts:                arg.charts,
+			ConditionalFormatting: arg.conditional,
+			NamedRanges:           arg.namedRanges,
+			NamedRangePrefix:      arg.rangePrefix,
+			Template:              arg.template,
+			Create:                arg.create,
+			Folder:                arg.folder,
+			DryRun:                arg.dryRun,
+			Habits:                parseHabits(arg.habitsStr),
+		})
+		failOnErr(err)
+		return
+	}
+
+	cmd := application.SyncCMD{
+		Prefix:                arg.prefix,
+		From:                  arg.from,
+		To:                    arg.to,
+		SheetName:             arg.sheetName,
+		Spreadsheet:           arg.spreadsheet,
+		Layout:                arg.layout,
+		Compare:               arg.compare,
+		Mode:                  arg.mode,
+		JournalSheetName:      arg.journalSheet,
+		Charts:                arg.charts,
+		ConditionalFormatting: arg.conditional,
+		NamedRanges:           arg.namedRanges,
+		NamedRangePrefix:      arg.rangePrefix,
+		Template:              arg.template,
+		Create:                arg.create,
+		Folder:                arg.folder,
+		DryRun:                arg.dryRun,
+		Habits:                parseHabits(arg.habitsStr),
+	}
+	if len(arg.targets) != 0 {
+		err = srv.HandleTargets(ctx, application.SyncTargetsCMD{
+			SyncCMD:     cmd,
+			Targets:     arg.targets,
+			Parallelism: arg.parallelism,
+		})
+	} else {
+		err = srv.Handle(ctx, cmd)
+	}
 	failOnErr(err)
 }
 
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"habitsSync/internal/application"
+	"habitsSync/internal/domain"
+	"habitsSync/internal/infrastructure/auth"
+	"habitsSync/internal/infrastructure/drive"
+	"os"
+)
+
+// readHabits reads the backups with the account of the arguments, which only
+// needs to be authorized to read them
+func readHabits(ctx context.Context, arg args) *domain.Habits {
+	credentials := auth.Credentials{Path: arg.credentialsPath, TokenPath: arg.tokenPath, Subject: arg.subject}
+	r, err := drive.NewRepository(ctx, auth.NewProvider(credentials, auth.ReadBackups))
+	failOnErr(err)
+	return domain.NewHabits(drive.NewDBFile(arg.tmpPath), drive.NewStorageFactory(arg.tmpPath), r)
+}
+
+func backupsListCommand(argv []string) {
+	a := args{}
+	fs := newFlagSet("backups list")
+	accountFlags(fs, &a)
+	driveFlags(fs, &a)
+	parse(fs, argv)
+	failOnErr(parsePaths(&a))
+
+	ctx, cancel := interruptible(a.timeout)
+	defer cancel()
+	habits := readHabits(ctx, a)
+	srv := application.NewListService(habits, habits, os.Stdout)
+	failOnErr(srv.Backups(ctx, application.BackupsCMD{Prefix: a.prefix}))
+}
+
+func habitsListCommand(argv []string) {
+	a := args{}
+	fs := newFlagSet("habits list")
+	accountFlags(fs, &a)
+	driveFlags(fs, &a)
+	backupFlags(fs, &a)
+	parse(fs, argv)
+	failOnErr(parsePaths(&a))
+	failOnErr(parseDates(&a))
+
+	ctx, cancel := interruptible(a.timeout)
+	defer cancel()
+	habits := readHabits(ctx, a)
+	srv := application.NewListService(habits, habits, os.Stdout)
+	failOnErr(srv.Habits(ctx, application.HabitsCMD{Prefix: a.prefix, From: a.from, To: a.to}))
+}
@@ -1,12 +1,132 @@
 package main
 
-func main() {
-	arg := parseArgs()
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+)
+
+// version is set when building, see the Makefile
+var version = "dev"
+
+type command struct {
+	// name is one or more words, like "backups list"
+	name        string
+	description string
+	run         func(argv []string)
+}
+
+// commands are set on init, as their flags describe them from this list
+var commands []command
 
-	if arg.authorize {
-		authorize(arg)
+func init() {
+	commands = []command{
+		{name: "auth", description: "authorize the access to your Google account", run: authCommand},
+		{name: "auth status", description: "show the account, the permissions and the expiry of the authorization", run: authStatusCommand},
+		{name: "auth revoke", description: "revoke the authorization and delete its token", run: authRevokeCommand},
+		{name: "sync", description: "import the habits of the latest backup into a spreadsheet", run: syncCommand},
+		{name: "backups list", description: "list the backups found on Google Drive", run: backupsListCommand},
+		{name: "habits list", description: "list the habits of the latest backup", run: habitsListCommand},
+		{name: "report", description: "print the report of the habits on the terminal", run: reportCommand},
+		{name: "export", description: "export the report of the habits as CSV or JSON", run: exportCommand},
+		{name: "version", description: "print the version", run: versionCommand},
+	}
+}
+
+func main() {
+	argv := os.Args[1:]
+	if len(argv) != 0 && strings.HasPrefix(argv[0], "-") && !isHelp(argv[0]) {
+		legacyCommand(argv)
 		return
 	}
+	if len(argv) == 0 || isHelp(argv[0]) || argv[0] == "help" {
+		usage(os.Stdout)
+		return
+	}
+
+	c, rest, ok := findCommand(argv)
+	if !ok {
+		_, _ = fmt.Fprintf(os.Stderr, "unknown command %q\n\n", strings.Join(argv, " "))
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+	c.run(rest)
+}
+
+// findCommand returns the command with the most words matching the first
+// arguments, and the rest of them
+func findCommand(argv []string) (command, []string, bool) {
+	found, words := command{}, 0
+	for _, c := range commands {
+		name := strings.Fields(c.name)
+		if len(name) <= words || len(name) > len(argv) {
+			continue
+		}
+		if strings.Join(argv[:len(name)], " ") == c.name {
+			found, words = c, len(name)
+		}
+	}
+	return found, argv[words:], words != 0
+}
+
+func isHelp(arg string) bool {
+	return arg == "-h" || arg == "-help" || arg == "--help"
+}
+
+func usage(w io.Writer) {
+	_, _ = fmt.Fprint(w, "Usage: hsync <command> [flags]\n\nCommands:\n")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, c := range commands {
+		_, _ = fmt.Fprintf(tw, "  %v\t%v\n", c.name, c.description)
+	}
+	_ = tw.Flush()
+	_, _ = fmt.Fprint(w, "\nRun hsync <command> -h for the flags of a command\n")
+}
+
+// newFlagSet returns the flags of the command, its help listing them
+func newFlagSet(name string) *flag.FlagSet {
+	c, _, _ := findCommand(strings.Fields(name))
+	fs := flag.NewFlagSet("hsync "+name, flag.ExitOnError)
+	fs.Usage = func() {
+		out := fs.Output()
+		_, _ = fmt.Fprintf(out, "Usage: hsync %v [flags]\n\n%v\n\nFlags:\n", name, strings.ToUpper(c.description[:1])+c.description[1:])
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func versionCommand(argv []string) {
+	parse(newFlagSet("version"), argv)
+	fmt.Printf("hsync %v %v/%v\n", version, runtime.GOOS, runtime.GOARCH)
+}
+
+// parse fails on the arguments left after the flags, as no command takes any
+func parse(fs *flag.FlagSet, argv []string) {
+	_ = fs.Parse(argv)
+	if err := noArguments(fs); err != nil {
+		_, _ = fmt.Fprintln(fs.Output(), err)
+		fs.Usage()
+		os.Exit(2)
+	}
+}
+
+func noArguments(fs *flag.FlagSet) error {
+	if fs.NArg() != 0 {
+		return fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	return nil
+}
 
-	importData(arg)
+// setFlags returns the names of the flags given on the command line, to tell
+// them apart from the ones left to their default
+func setFlags(fs *flag.FlagSet) map[string]bool {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	return set
 }
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"habitsSync/internal/domain"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestFindCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		argv     []string
+		want     string
+		wantRest []string
+		wantOk   bool
+	}{
+		{name: "one word", argv: []string{"sync", "-dry-run"}, want: "sync", wantRest: []string{"-dry-run"}, wantOk: true},
+		{name: "most words", argv: []string{"auth", "status"}, want: "auth status", wantRest: []string{}, wantOk: true},
+		{name: "flags of the shorter one", argv: []string{"auth", "-headless"}, want: "auth", wantRest: []string{"-headless"}, wantOk: true},
+		{name: "first word only", argv: []string{"backups"}, wantRest: []string{"backups"}},
+		{name: "unknown", argv: []string{"import", "sync"}, wantRest: []string{"import", "sync"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rest, ok := findCommand(tt.argv)
+			if got.name != tt.want || ok != tt.wantOk {
+				t.Errorf("findCommand() = %q, %v, want %q, %v", got.name, ok, tt.want, tt.wantOk)
+			}
+			if !reflect.DeepEqual(rest, tt.wantRest) {
+				t.Errorf("findCommand() left %q, want %q", rest, tt.wantRest)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		argv    []string
+		want    string
+		wantErr bool
+	}{
+		{name: "flags", argv: []string{"-prefix", "Backup"}, want: "Backup"},
+		{name: "no flags", want: "Loop Habits Backup"},
+		{name: "argument after the flags", argv: []string{"-prefix", "Backup", "Import"}, wantErr: true},
+		{name: "argument instead of a flag", argv: []string{"Import"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := args{}
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.SetOutput(ioutil.Discard)
+			driveFlags(fs, &a)
+			if tt.wantErr {
+				// parse exits on the arguments left, see noArguments
+				if err := fs.Parse(tt.argv); err != nil {
+					t.Fatal(err)
+				}
+				if err := noArguments(fs); err == nil {
+					t.Errorf("noArguments() of %q = nil, want an error", tt.argv)
+				}
+				return
+			}
+
+			parse(fs, tt.argv)
+			if a.prefix != tt.want {
+				t.Errorf("parse() prefix = %q, want %q", a.prefix, tt.want)
+			}
+		})
+	}
+}
+
+func TestLegacyFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		argv    []string
+		check   func(a args) bool
+		wantSet map[string]bool
+	}{
+		{
+			name:    "authorize",
+			argv:    []string{"-auth", "-headless"},
+			check:   func(a args) bool { return a.authorize && a.headless },
+			wantSet: map[string]bool{"auth": true, "headless": true},
+		},
+		{
+			name: "sync",
+			argv: []string{"-spreadsheet", "Habits", "-sheet-name", "Q1", "-mode", "merge", "-journal"},
+			check: func(a args) bool {
+				return !a.authorize && a.spreadsheet == "Habits" && a.sheetName == "Q1" &&
+					a.mode == domain.MergeMode && a.journalSheet == "Journal"
+			},
+			wantSet: map[string]bool{"spreadsheet": true, "sheet-name": true, "mode": true, "journal": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := args{}
+			fs := legacyFlags(&a)
+			fs.Init("hsync", flag.ContinueOnError)
+			fs.SetOutput(ioutil.Discard)
+			if err := fs.Parse(tt.argv); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if err := parseSync(&a); err != nil {
+				t.Fatalf("parseSync() error = %v", err)
+			}
+			if !tt.check(a) {
+				t.Errorf("legacyFlags() parsed %q into %+v", tt.argv, a)
+			}
+			if set := setFlags(fs); !reflect.DeepEqual(set, tt.wantSet) {
+				t.Errorf("setFlags() = %v, want %v", set, tt.wantSet)
+			}
+		})
+	}
+}
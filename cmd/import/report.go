@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"habitsSync/internal/application"
+	"habitsSync/internal/domain"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+func reportCommand(argv []string) {
+	a := args{}
+	fs := newFlagSet("report")
+	accountFlags(fs, &a)
+	driveFlags(fs, &a)
+	backupFlags(fs, &a)
+	reportFlags(fs, &a)
+	parse(fs, argv)
+	failOnErr(parsePaths(&a))
+	failOnErr(parseReport(&a))
+
+	ctx, cancel := interruptible(a.timeout)
+	defer cancel()
+	failOnErr(report(ctx, readHabits(ctx, a), a, application.TextFormat, os.Stdout))
+}
+
+func exportCommand(argv []string) {
+	a := args{}
+	fs := newFlagSet("export")
+	accountFlags(fs, &a)
+	driveFlags(fs, &a)
+	backupFlags(fs, &a)
+	reportFlags(fs, &a)
+	fs.StringVar(&a.formatStr, "format", "csv", "format of the file: csv or json")
+	fs.StringVar(&a.output, "output", "-", "file where the report is exported, - for the standard output")
+	parse(fs, argv)
+	failOnErr(parsePaths(&a))
+	failOnErr(parseReport(&a))
+	format, err := application.ParseReportFormat(a.formatStr)
+	failOnErr(err)
+
+	ctx, cancel := interruptible(a.timeout)
+	defer cancel()
+	if a.output == "-" {
+		failOnErr(report(ctx, readHabits(ctx, a), a, format, os.Stdout))
+		return
+	}
+	failOnErr(export(ctx, readHabits(ctx, a), a, format))
+}
+
+// export writes the report to a temporary file renamed to the output once
+// complete, so a failed export leaves no file half written
+func export(ctx context.Context, h *domain.Habits, arg args, format application.ReportFormat) (err error) {
+	f, err := ioutil.TempFile(filepath.Dir(arg.output), "."+filepath.Base(arg.output)+"-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(f.Name())
+		}
+	}()
+
+	// TempFile creates it private, unlike os.Create
+	if err = f.Chmod(0644); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err = report(ctx, h, arg, format, f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), arg.output)
+}
+
+func report(ctx context.Context, h *domain.Habits, arg args, format application.ReportFormat, out io.Writer) error {
+	srv := application.NewReportService(h, out)
+	return srv.Handle(ctx, application.ReportCMD{
+		Prefix:  arg.prefix,
+		From:    arg.from,
+		To:      arg.to,
+		Layout:  arg.layout,
+		Compare: arg.compare,
+		Habits:  parseHabits(arg.habitsStr),
+		Format:  format,
+	})
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// interruptible returns a context canceled on SIGINT or SIGTERM, or after the
+// timeout if any. The calls in flight are canceled and the command stops; a
+// second signal exits at once
+func interruptible(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.Background(), context.CancelFunc(nil)
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-signals:
+		case <-ctx.Done():
+			signal.Stop(signals)
+			return
+		}
+		_, _ = fmt.Fprintln(os.Stderr, "Stopping... press Ctrl-C again to exit now")
+		cancel()
+		<-signals
+		os.Exit(130)
+	}()
+
+	return ctx, cancel
+}
@@ -4,7 +4,7 @@ go 1.15
 
 require (
 	github.com/mattn/go-sqlite3 v1.14.6
-	golang.org/x/net v0.0.0-20201031054903-ff519b6c9102
+	golang.org/x/crypto v0.0.0-20201221181555-eec23a3978ad
 	golang.org/x/oauth2 v0.0.0-20201208152858-08078c50e5b5
 	google.golang.org/api v0.36.0
 )
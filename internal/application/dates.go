@@ -3,6 +3,8 @@ package application
 import (
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -122,3 +124,148 @@ func endOfDay(t time.Time) time.Time {
 	y, m, d := t.Date()
 	return time.Date(y, m, d, 23, 59, 59, int(time.Second-time.Nanosecond), t.Location())
 }
+
+type Comparison int
+
+const (
+	NoComparison Comparison = iota
+	PreviousPeriod
+	SamePeriodLastYear
+)
+
+func ParseComparison(s string) (Comparison, error) {
+	switch s {
+	case "":
+		return NoComparison, nil
+	case "previous":
+		return PreviousPeriod, nil
+	case "last-year":
+		return SamePeriodLastYear, nil
+	}
+	return NoComparison, fmt.Errorf("invalid comparison %q. valid comparisons are previous and last-year", s)
+}
+
+// Range returns the dates of the period to compare [from, to] against
+func (c Comparison) Range(from, to time.Time) (time.Time, time.Time) {
+	switch c {
+	case PreviousPeriod:
+		return previousPeriod(from, to)
+	case SamePeriodLastYear:
+		return shiftYears(from, -1), shiftYears(to, -1)
+	}
+	return from, to
+}
+
+// previousPeriod returns the range of the same length that ends right before
+// from. Ranges made of whole months (quarters) are shifted by whole months so
+// the previous quarter is not off by a few days
+func previousPeriod(from, to time.Time) (time.Time, time.Time) {
+	if isMonthStart(from) && isMonthEnd(to) {
+		months := (to.Year()-from.Year())*12 + int(to.Month()-from.Month()) + 1
+		return from.AddDate(0, -months, 0), from.Add(-time.Nanosecond)
+	}
+	length := to.Sub(from)
+	end := from.Add(-time.Nanosecond)
+	return end.Add(-length), end
+}
+
+func isMonthStart(t time.Time) bool {
+	return t.Day() == 1 && t.Equal(startOfDay(t))
+}
+
+func isMonthEnd(t time.Time) bool {
+	return t.Equal(endOfDay(t)) && t.AddDate(0, 0, 1).Day() == 1
+}
+
+// shiftYears moves t the given years, keeping it on the last day of February
+// when the target year is not a leap year
+func shiftYears(t time.Time, years int) time.Time {
+	y, m, d := t.Date()
+	first := time.Date(y+years, m, 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	if last := first.AddDate(0, 1, -1).Day(); d > last {
+		d = last
+	}
+	return first.AddDate(0, 0, d-1)
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// Period is a named date range. The name is used as the Sheet name
+type Period struct {
+	Name string
+	From time.Time
+	To   time.Time
+}
+
+type PeriodsCMD struct {
+	Every    string // quarter or month
+	Year     int    // Defaults to the current year
+	Template string // Defaults to "Q{q} {yyyy}" or "{month} {yyyy}"
+}
+
+var defaultTemplates = map[string]string{
+	"quarter": "Q{q} {yyyy}",
+	"month":   "{month} {yyyy}",
+}
+
+// Periods splits a whole year in quarters or months, naming each one of them
+// from the template. Supported placeholders: {yyyy}, {yy}, {q}, {m}, {mm},
+// {month} and {mon}
+func (s *DatesService) Periods(cmd PeriodsCMD) ([]Period, error) {
+	year := cmd.Year
+	if year == 0 {
+		year = s.timeRepository.Now().Year()
+	}
+
+	template := cmd.Template
+	if template == "" {
+		template = defaultTemplates[cmd.Every]
+	}
+
+	periods := make([]Period, 0)
+	switch cmd.Every {
+	case "quarter":
+		for i, q := range makeQuarters(year) {
+			periods = append(periods, Period{
+				Name: periodName(template, q.from, i+1),
+				From: q.from,
+				To:   q.to,
+			})
+		}
+	case "month":
+		for m := time.January; m <= time.December; m++ {
+			from := time.Date(year, m, 1, 0, 0, 0, 0, time.UTC)
+			periods = append(periods, Period{
+				Name: periodName(template, from, (int(m)-1)/3+1),
+				From: from,
+				To:   endOfDay(from.AddDate(0, 1, -1)),
+			})
+		}
+	default:
+		return nil, fmt.Errorf("invalid period %q. valid periods are quarter and month", cmd.Every)
+	}
+
+	names := make(map[string]bool, len(periods))
+	for _, p := range periods {
+		if names[p.Name] {
+			return nil, fmt.Errorf("the template %q names several periods %q, tell them apart with {q} or {month}", template, p.Name)
+		}
+		names[p.Name] = true
+	}
+	return periods, nil
+}
+
+func periodName(template string, from time.Time, quarter int) string {
+	return strings.NewReplacer(
+		"{yyyy}", from.Format("2006"),
+		"{yy}", from.Format("06"),
+		"{q}", strconv.Itoa(quarter),
+		"{mm}", from.Format("01"),
+		"{m}", from.Format("1"),
+		"{month}", from.Format("January"),
+		"{mon}", from.Format("Jan"),
+	).Replace(template)
+}
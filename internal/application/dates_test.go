@@ -134,3 +134,102 @@ func TestDatesService_Handle(t *testing.T) {
 		})
 	}
 }
+
+func TestParseComparison(t *testing.T) {
+	tests := []struct {
+		name    string
+		arg     string
+		want    application.Comparison
+		wantErr bool
+	}{
+		{name: "no comparison", arg: "", want: application.NoComparison},
+		{name: "previous period", arg: "previous", want: application.PreviousPeriod},
+		{name: "same period last year", arg: "last-year", want: application.SamePeriodLastYear},
+		{name: "fail on unknown comparison", arg: "yesterday", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := application.ParseComparison(tt.arg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseComparison() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseComparison() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDatesService_Periods(t *testing.T) {
+	tests := []struct {
+		name      string
+		cmd       application.PeriodsCMD
+		wantNames []string
+		wantErr   bool
+	}{
+		{
+			name:      "quarters of the current year",
+			cmd:       application.PeriodsCMD{Every: "quarter"},
+			wantNames: []string{"Q1 2021", "Q2 2021", "Q3 2021", "Q4 2021"},
+		},
+		{
+			name: "months of a given year with a template",
+			cmd: application.PeriodsCMD{
+				Every:    "month",
+				Year:     2020,
+				Template: "{yyyy}-{mm} ({mon}, Q{q})",
+			},
+			wantNames: []string{
+				"2020-01 (Jan, Q1)", "2020-02 (Feb, Q1)", "2020-03 (Mar, Q1)",
+				"2020-04 (Apr, Q2)", "2020-05 (May, Q2)", "2020-06 (Jun, Q2)",
+				"2020-07 (Jul, Q3)", "2020-08 (Aug, Q3)", "2020-09 (Sep, Q3)",
+				"2020-10 (Oct, Q4)", "2020-11 (Nov, Q4)", "2020-12 (Dec, Q4)",
+			},
+		},
+		{
+			name:    "fail on a template naming several months the same",
+			cmd:     application.PeriodsCMD{Every: "month", Template: "Q{q} {yyyy}"},
+			wantErr: true,
+		},
+		{
+			name:    "fail on unknown period",
+			cmd:     application.PeriodsCMD{Every: "week"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := application.NewDatesService(&testTimeRepository{})
+			got, err := s.Periods(tt.cmd)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Periods() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			names := make([]string, 0)
+			for _, p := range got {
+				names = append(names, p.Name)
+			}
+			if len(names) != len(tt.wantNames) {
+				t.Fatalf("Periods() got %v, want %v", names, tt.wantNames)
+			}
+			for i := range names {
+				if names[i] != tt.wantNames[i] {
+					t.Errorf("Periods() got %v, want %v", names, tt.wantNames)
+					break
+				}
+			}
+		})
+	}
+
+	t.Run("months cover the whole month", func(t *testing.T) {
+		s := application.NewDatesService(&testTimeRepository{})
+		got, err := s.Periods(application.PeriodsCMD{Every: "month", Year: 2020})
+		if err != nil {
+			t.Fatalf("Periods() error = %v", err)
+		}
+		feb := got[1]
+		wantTo := time.Date(2020, 02, 29, 23, 59, 59, 999999999, time.UTC)
+		if !feb.To.Equal(wantTo) {
+			t.Errorf("February ends at %v, want %v", feb.To, wantTo)
+		}
+	})
+}
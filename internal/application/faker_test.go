@@ -1,20 +1,83 @@
 package application_test
 
-import "habitsSync/internal/domain"
+import (
+	"context"
+	"habitsSync/internal/domain"
+	"sync"
+	"time"
+)
 
 type fakeHabitsGetter struct {
-	habits []domain.Habit
-	err    error
+	habits      []domain.Habit
+	repetitions []domain.Repetition
+	journal     []domain.JournalEntry
+	err         error
+	opened      int
+	calls       []domain.GetAllCMD
+	mu          sync.Mutex
 }
 
-func (f *fakeHabitsGetter) GetAll(cmd domain.GetAllCMD) ([]domain.Habit, error) {
-	return f.habits, f.err
+func (f *fakeHabitsGetter) Open(ctx context.Context, prefix string) (*domain.Backup, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.opened++
+	return &domain.Backup{Name: prefix, Storage: f}, nil
+}
+
+func (f *fakeHabitsGetter) AllHabits(ctx context.Context, from, to time.Time) ([]domain.Habit, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, domain.GetAllCMD{From: from, To: to})
+	return f.habits, nil
+}
+
+func (f *fakeHabitsGetter) Repetitions(ctx context.Context, from, to time.Time) ([]domain.Repetition, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, domain.GetAllCMD{From: from, To: to})
+	return f.repetitions, nil
+}
+
+func (f *fakeHabitsGetter) Journal(ctx context.Context, from, to time.Time) ([]domain.JournalEntry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, domain.GetAllCMD{From: from, To: to})
+	return f.journal, nil
 }
 
 type fakeSpreadsheetUpdater struct {
-	err error
+	err      error
+	calls    []domain.UpdateCMD
+	existing domain.Table
+	reads    []domain.ReadCMD
+	// sheetErrs fail the updates of some Sheets only
+	sheetErrs map[string]error
+	mu        sync.Mutex
 }
 
-func (f *fakeSpreadsheetUpdater) Update(cmd domain.UpdateCMD) error {
+func (f *fakeSpreadsheetUpdater) Read(ctx context.Context, cmd domain.ReadCMD) (domain.Table, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reads = append(f.reads, cmd)
+	return f.existing, f.err
+}
+
+func (f *fakeSpreadsheetUpdater) Update(ctx context.Context, cmd domain.UpdateCMD) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, cmd)
+	if err, ok := f.sheetErrs[cmd.SheetName]; ok {
+		return err
+	}
 	return f.err
 }
+
+type fakeBackupsLister struct {
+	files []domain.File
+	err   error
+}
+
+func (f *fakeBackupsLister) Backups(ctx context.Context, prefix string) ([]domain.File, error) {
+	return f.files, f.err
+}
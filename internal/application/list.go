@@ -0,0 +1,94 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"habitsSync/internal/domain"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+type BackupsLister interface {
+	Backups(ctx context.Context, prefix string) ([]domain.File, error)
+}
+
+// ListService shows what there is to import, without writing anything
+type ListService struct {
+	backupsLister BackupsLister
+	habitsGetter  HabitsGetter
+	output        io.Writer
+}
+
+func NewListService(b BackupsLister, h HabitsGetter, out io.Writer) *ListService {
+	return &ListService{
+		backupsLister: b,
+		habitsGetter:  h,
+		output:        out,
+	}
+}
+
+type BackupsCMD struct {
+	Prefix string
+}
+
+// Backups prints the backups found with the prefix, marking the one imported
+func (s *ListService) Backups(ctx context.Context, cmd BackupsCMD) error {
+	files, err := s.backupsLister.Backups(ctx, cmd.Prefix)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no backup found with prefix '%v'", cmd.Prefix)
+	}
+
+	w := tabwriter.NewWriter(s.output, 0, 0, 2, ' ', 0)
+	for i, f := range files {
+		imported := ""
+		if i == 0 {
+			imported = "(imported)"
+		}
+		modified := ""
+		if !f.Modified.IsZero() {
+			modified = f.Modified.Local().Format("2006-01-02 15:04")
+		}
+		if _, err := fmt.Fprintf(w, "%v\t%v\t%v\n", f.Name, modified, imported); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+type HabitsCMD struct {
+	Prefix string
+	From   time.Time
+	To     time.Time
+}
+
+// Habits prints the habits of the backup imported, with their repetitions
+// on the range
+func (s *ListService) Habits(ctx context.Context, cmd HabitsCMD) error {
+	backup, err := s.habitsGetter.Open(ctx, cmd.Prefix)
+	if err != nil {
+		return err
+	}
+	habits, err := backup.AllHabits(ctx, cmd.From, cmd.To)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(s.output, "%v habits on %v, from %v to %v\n",
+		len(habits), backup.Name, cmd.From.Format(dateLayout), cmd.To.Format(dateLayout)); err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(s.output, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprint(w, "ID\tName\tCount\n"); err != nil {
+		return err
+	}
+	for _, h := range habits {
+		if _, err := fmt.Fprintf(w, "%v\t%v\t%v\n", h.ID, h.Name, h.Count); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
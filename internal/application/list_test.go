@@ -0,0 +1,73 @@
+package application_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"habitsSync/internal/application"
+	"habitsSync/internal/domain"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListService_Backups(t *testing.T) {
+	tests := []struct {
+		name    string
+		lister  *fakeBackupsLister
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "mark the imported backup",
+			lister: &fakeBackupsLister{files: []domain.File{
+				{ID: "1", Name: "Loop Habits Backup 2021-03-01", Modified: time.Date(2021, 3, 1, 10, 0, 0, 0, time.Local)},
+				{ID: "2", Name: "Loop Habits Backup 2021-02-01"},
+			}},
+			want: []string{
+				"Loop Habits Backup 2021-03-01  2021-03-01 10:00  (imported)",
+				"Loop Habits Backup 2021-02-01",
+			},
+		},
+		{name: "fail when no backup is found", lister: &fakeBackupsLister{}, wantErr: true},
+		{name: "fail when listing fails", lister: &fakeBackupsLister{err: errors.New("fake list error")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			s := application.NewListService(tt.lister, &fakeHabitsGetter{}, out)
+			err := s.Backups(context.Background(), application.BackupsCMD{Prefix: "Loop Habits Backup"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Backups() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+			for i, want := range tt.want {
+				if strings.TrimRight(lines[i], " ") != want {
+					t.Errorf("Backups() printed %q, want %q", lines[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestListService_Habits(t *testing.T) {
+	out := &bytes.Buffer{}
+	h := &fakeHabitsGetter{habits: []domain.Habit{{ID: 1, Name: "Run", Count: 3}, {ID: 2, Name: "Read", Count: 12}}}
+	s := application.NewListService(&fakeBackupsLister{}, h, out)
+	from, to := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2021, 3, 31, 0, 0, 0, 0, time.UTC)
+
+	err := s.Habits(context.Background(), application.HabitsCMD{Prefix: "backup", From: from, To: to})
+	if err != nil {
+		t.Fatalf("Habits() error = %v", err)
+	}
+	want := "2 habits on backup, from 2021-01-01 to 2021-03-31\n" +
+		"ID  Name  Count\n" +
+		"1   Run   3\n" +
+		"2   Read  12\n"
+	if out.String() != want {
+		t.Errorf("Habits() printed\n%v\nwant\n%v", out, want)
+	}
+	if len(h.calls) != 1 || !h.calls[0].From.Equal(from) || !h.calls[0].To.Equal(to) {
+		t.Errorf("Habits() asked %v, want the range", h.calls)
+	}
+}
@@ -0,0 +1,196 @@
+package application
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"habitsSync/internal/domain"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// ReportFormat is how the report is printed
+type ReportFormat string
+
+const (
+	TextFormat ReportFormat = "text"
+	CSVFormat  ReportFormat = "csv"
+	JSONFormat ReportFormat = "json"
+)
+
+func ParseReportFormat(s string) (ReportFormat, error) {
+	switch f := ReportFormat(s); f {
+	case "":
+		return TextFormat, nil
+	case TextFormat, CSVFormat, JSONFormat:
+		return f, nil
+	}
+	return "", fmt.Errorf("invalid format %q. valid formats are %v, %v and %v", s, TextFormat, CSVFormat, JSONFormat)
+}
+
+// ReportService prints the report written on the Sheets by the SyncService,
+// to read it on the terminal or to export it
+type ReportService struct {
+	habitsGetter HabitsGetter
+	output       io.Writer
+}
+
+func NewReportService(h HabitsGetter, out io.Writer) *ReportService {
+	return &ReportService{
+		habitsGetter: h,
+		output:       out,
+	}
+}
+
+type ReportCMD struct {
+	Prefix  string
+	From    time.Time
+	To      time.Time
+	Layout  Layout
+	Compare Comparison
+	// Habits are the names of the habits to report. Empty for all of them
+	Habits []string
+	Format ReportFormat
+}
+
+func (c *ReportCMD) Validate() error {
+	if c.Layout == WeekdaysLayout && c.Compare != NoComparison {
+		return errors.New("comparisons are only available for the habits layout")
+	}
+	return nil
+}
+
+func (s *ReportService) Handle(ctx context.Context, cmd ReportCMD) error {
+	if err := cmd.Validate(); err != nil {
+		return err
+	}
+
+	backup, err := s.habitsGetter.Open(ctx, cmd.Prefix)
+	if err != nil {
+		return err
+	}
+	b := tableBuilder{backup: backup, filter: newHabitFilter(cmd.Habits), output: ioutil.Discard}
+	table, err := b.build(ctx, cmd.Layout, cmd.Compare, cmd.From, cmd.To)
+	if err != nil {
+		return err
+	}
+
+	switch cmd.Format {
+	case CSVFormat:
+		return s.csv(table)
+	case JSONFormat:
+		return s.json(table)
+	default:
+		table.Title = title(backup, cmd.From, cmd.To)
+		return s.text(table)
+	}
+}
+
+func (s *ReportService) text(t domain.Table) error {
+	if _, err := fmt.Fprintln(s.output, t.Title); err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(s.output, 0, 0, 2, ' ', 0)
+	for i, c := range t.Columns {
+		if _, err := fmt.Fprint(w, separator(i), c.Name); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintln(w); err != nil {
+		return err
+	}
+	for _, row := range t.Rows {
+		for i, v := range row {
+			if _, err := fmt.Fprint(w, separator(i), textValue(t.Columns[i], domain.Value(v))); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+func separator(column int) string {
+	if column == 0 {
+		return ""
+	}
+	return "\t"
+}
+
+// textValue shows the value as it's formatted on the Sheet
+func textValue(c domain.Column, v interface{}) string {
+	switch v := v.(type) {
+	case float64:
+		if c.Format == domain.PercentFormat {
+			return strconv.FormatFloat(v*100, 'f', 1, 64) + "%"
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		if c.Format == domain.DateFormat {
+			return v.Format(dateLayout)
+		}
+		return v.Format("2006-01-02 15:04")
+	}
+	return fmt.Sprint(v)
+}
+
+// csv writes the header and the raw values, as the rates are left to format
+// to whoever opens the file
+func (s *ReportService) csv(t domain.Table) error {
+	w := csv.NewWriter(s.output)
+	header := make([]string, 0, len(t.Columns))
+	for _, c := range t.Columns {
+		header = append(header, c.Name)
+	}
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	for _, row := range t.Rows {
+		record := make([]string, 0, len(row))
+		for _, v := range row {
+			record = append(record, rawValue(domain.Value(v)))
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func rawValue(v interface{}) string {
+	switch v := v.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	}
+	return fmt.Sprint(v)
+}
+
+// json writes an object per row, keyed by the names of the columns. Values
+// that cannot be computed, like the change against nothing, are null
+func (s *ReportService) json(t domain.Table) error {
+	rows := make([]map[string]interface{}, 0, len(t.Rows))
+	for _, row := range t.Rows {
+		object := make(map[string]interface{}, len(row))
+		for i, v := range row {
+			v = domain.Value(v)
+			if v == "" && t.Columns[i].Format == domain.PercentFormat {
+				v = nil
+			}
+			object[t.Columns[i].Name] = v
+		}
+		rows = append(rows, object)
+	}
+	e := json.NewEncoder(s.output)
+	e.SetIndent("", "  ")
+	return e.Encode(rows)
+}
@@ -0,0 +1,120 @@
+package application_test
+
+import (
+	"bytes"
+	"context"
+	"habitsSync/internal/application"
+	"habitsSync/internal/domain"
+	"testing"
+	"time"
+)
+
+func TestReportService_Handle(t *testing.T) {
+	habits := []domain.Habit{{ID: 1, Name: "Run", Count: 3}, {ID: 2, Name: "Read", Count: 12}}
+	from, to := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2021, 3, 31, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		cmd     application.ReportCMD
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "text",
+			cmd:  application.ReportCMD{Format: application.TextFormat},
+			want: "Imported from backup, from 2021-01-01 to 2021-03-31\n" +
+				"ID  Name  Count  Question  Description\n" +
+				"1   Run   3                \n" +
+				"2   Read  12               \n",
+		},
+		{
+			name: "csv of the selected habits",
+			cmd:  application.ReportCMD{Format: application.CSVFormat, Habits: []string{"read"}},
+			want: "ID,Name,Count,Question,Description\n" +
+				"2,Read,12,,\n",
+		},
+		{
+			name: "json comparison",
+			cmd:  application.ReportCMD{Format: application.JSONFormat, Compare: application.PreviousPeriod, Habits: []string{"run"}},
+			want: "[\n" +
+				"  {\n" +
+				"    \"Change\": 0,\n" +
+				"    \"Count\": 3,\n" +
+				"    \"Delta\": 0,\n" +
+				"    \"ID\": 1,\n" +
+				"    \"Name\": \"Run\",\n" +
+				"    \"Previous\": 3,\n" +
+				"    \"Status\": \"On track\"\n" +
+				"  }\n" +
+				"]\n",
+		},
+		{
+			name:    "fail comparing weekdays",
+			cmd:     application.ReportCMD{Layout: application.WeekdaysLayout, Compare: application.PreviousPeriod},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			s := application.NewReportService(&fakeHabitsGetter{habits: habits}, out)
+			tt.cmd.Prefix, tt.cmd.From, tt.cmd.To = "backup", from, to
+			err := s.Handle(context.Background(), tt.cmd)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Handle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if out.String() != tt.want {
+				t.Errorf("Handle() printed\n%v\nwant\n%v", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestReportService_Handle_weekdays(t *testing.T) {
+	monday := time.Date(2021, 1, 4, 9, 0, 0, 0, time.UTC)
+	h := &fakeHabitsGetter{repetitions: []domain.Repetition{
+		{HabitID: 1, HabitName: "Run", Timestamp: monday},
+		{HabitID: 1, HabitName: "Run", Timestamp: monday.AddDate(0, 0, 7)},
+	}}
+	out := &bytes.Buffer{}
+	s := application.NewReportService(h, out)
+
+	err := s.Handle(context.Background(), application.ReportCMD{
+		Prefix: "backup",
+		From:   monday,
+		To:     monday.AddDate(0, 0, 13),
+		Layout: application.WeekdaysLayout,
+		Format: application.CSVFormat,
+	})
+	if err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	want := "ID,Name,Mon,Tue,Wed,Thu,Fri,Sat,Sun,Mon %,Tue %,Wed %,Thu %,Fri %,Sat %,Sun %\n" +
+		"1,Run,2,0,0,0,0,0,0,1,0,0,0,0,0,0\n"
+	if out.String() != want {
+		t.Errorf("Handle() printed\n%v\nwant\n%v", out, want)
+	}
+}
+
+func TestParseReportFormat(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    application.ReportFormat
+		wantErr bool
+	}{
+		{s: "", want: application.TextFormat},
+		{s: "csv", want: application.CSVFormat},
+		{s: "json", want: application.JSONFormat},
+		{s: "xlsx", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := application.ParseReportFormat(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseReportFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseReportFormat() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,67 +1,325 @@
 package application
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"habitsSync/internal/domain"
 	"io"
+	"strings"
 	"time"
 )
 
 type HabitsGetter interface {
-	GetAll(cmd domain.GetAllCMD) ([]domain.Habit, error)
+	Open(ctx context.Context, prefix string) (*domain.Backup, error)
 }
 
 type SpreadsheetUpdater interface {
-	Update(cmd domain.UpdateCMD) error
+	Update(ctx context.Context, cmd domain.UpdateCMD) error
+	Read(ctx context.Context, cmd domain.ReadCMD) (domain.Table, error)
 }
 
 type SyncService struct {
 	habitsGetter       HabitsGetter
 	spreadsheetUpdater SpreadsheetUpdater
+	timeRepository     TimeRepository
 	output             io.Writer
 }
 
 func NewSyncService(
 	h HabitsGetter,
 	su SpreadsheetUpdater,
+	t TimeRepository,
 	out io.Writer,
 ) *SyncService {
 	return &SyncService{
 		habitsGetter:       h,
 		spreadsheetUpdater: su,
-		output:             out,
+		timeRepository:     t,
+		output:             &lockedWriter{w: out},
 	}
 }
 
+// Layout is the report written on the Sheet
+type Layout string
+
+const (
+	HabitsLayout   Layout = "habits"
+	WeekdaysLayout Layout = "weekdays"
+)
+
+func ParseLayout(s string) (Layout, error) {
+	switch l := Layout(s); l {
+	case "":
+		return HabitsLayout, nil
+	case HabitsLayout, WeekdaysLayout:
+		return l, nil
+	}
+	return "", fmt.Errorf("invalid layout %q. valid layouts are %v and %v", s, HabitsLayout, WeekdaysLayout)
+}
+
+func ParseWriteMode(s string) (domain.WriteMode, error) {
+	switch m := domain.WriteMode(s); m {
+	case "":
+		return domain.OverwriteMode, nil
+	case domain.OverwriteMode, domain.MergeMode, domain.AppendMode:
+		return m, nil
+	}
+	return "", fmt.Errorf("invalid mode %q. valid modes are %v, %v and %v",
+		s, domain.OverwriteMode, domain.MergeMode, domain.AppendMode)
+}
+
 type SyncCMD struct {
 	Prefix      string
 	From        time.Time
 	To          time.Time
 	Spreadsheet string
 	SheetName   string
+	Layout      Layout
+	Compare     Comparison
+	Mode        domain.WriteMode
+	// JournalSheetName is the Sheet where the notes are written. Empty to skip them
+	JournalSheetName      string
+	Charts                bool
+	ConditionalFormatting bool
+	NamedRanges           bool
+	NamedRangePrefix      string
+	Template              domain.Template
+	Create                bool
+	Folder                string
+	DryRun                bool
+	// Habits are the names of the habits to import. Empty for all of them
+	Habits []string
+}
+
+func (s *SyncService) Handle(ctx context.Context, cmd SyncCMD) error {
+	return s.HandlePeriods(ctx, cmd.periods())
+}
+
+// periods is the command for the one period of the SyncCMD
+func (cmd SyncCMD) periods() SyncPeriodsCMD {
+	return SyncPeriodsCMD{
+		Prefix:      cmd.Prefix,
+		Spreadsheet: cmd.Spreadsheet,
+		Periods: []Period{
+			{Name: cmd.SheetName, From: cmd.From, To: cmd.To},
+		},
+		Layout:                cmd.Layout,
+		Compare:               cmd.Compare,
+		Mode:                  cmd.Mode,
+		JournalSheetName:      cmd.JournalSheetName,
+		Charts:                cmd.Charts,
+		ConditionalFormatting: cmd.ConditionalFormatting,
+		NamedRanges:           cmd.NamedRanges,
+		NamedRangePrefix:      cmd.NamedRangePrefix,
+		Template:              cmd.Template,
+		Create:                cmd.Create,
+		Folder:                cmd.Folder,
+		DryRun:                cmd.DryRun,
+		Habits:                cmd.Habits,
+	}
+}
+
+type SyncPeriodsCMD struct {
+	Prefix      string
+	Spreadsheet string
+	Periods     []Period
+	Layout      Layout
+	Compare     Comparison
+	Mode        domain.WriteMode
+	// JournalSheetName is the Sheet where the notes of all the periods are
+	// written. Empty to skip them
+	JournalSheetName string
+	// Charts and ConditionalFormatting decorate the Sheets of the periods. They
+	// are not available in the append mode
+	Charts                bool
+	ConditionalFormatting bool
+	// NamedRanges keeps named ranges pointing to the imported data. With more
+	// than one period, their names are prefixed by the name of the period too
+	NamedRanges      bool
+	NamedRangePrefix string
+	// Template is the Sheet copied for the periods whose Sheet does not exist
+	Template domain.Template
+	// Create the spreadsheet when it does not exist, inside the Folder if any
+	Create bool
+	Folder string
+	// DryRun prints what would change on each Sheet instead of writing it
+	DryRun bool
+	// Habits are the names of the habits to import. Empty for all of them
+	Habits []string
+}
+
+func (c *SyncPeriodsCMD) Validate() error {
+	if c.Layout == WeekdaysLayout && c.Compare != NoComparison {
+		return errors.New("comparisons are only available for the habits layout")
+	}
+	sheets := make(map[string]bool, len(c.Periods))
+	for _, p := range c.Periods {
+		if sheets[p.Name] || (c.JournalSheetName != "" && p.Name == c.JournalSheetName) {
+			return fmt.Errorf("%v: the Sheet is written by another period or by the journal", p.Name)
+		}
+		sheets[p.Name] = true
+	}
+	return nil
 }
 
-func (s *SyncService) Handle(cmd SyncCMD) error {
-	habits, err := s.habitsGetter.GetAll(domain.GetAllCMD{
-		From: cmd.From,
-		To:   cmd.To,
-	})
+// HandlePeriods opens the backup once and writes each one of the periods on
+// its own Sheet
+func (s *SyncService) HandlePeriods(ctx context.Context, cmd SyncPeriodsCMD) error {
+	if err := cmd.Validate(); err != nil {
+		return err
+	}
+
+	backup, err := s.habitsGetter.Open(ctx, cmd.Prefix)
 	if err != nil {
 		return err
 	}
 
-	if _, err = fmt.Fprintf(s.output, "Importing %v habits...\n", len(habits)); err != nil {
+	return s.handlePeriods(ctx, backup, cmd)
+}
+
+func (s *SyncService) handlePeriods(ctx context.Context, backup *domain.Backup, cmd SyncPeriodsCMD) error {
+	for _, period := range cmd.Periods {
+		if err := s.sync(ctx, backup, cmd, period); err != nil {
+			return fmt.Errorf("%v: %w", period.Name, err)
+		}
+	}
+
+	if cmd.JournalSheetName != "" && len(cmd.Periods) != 0 {
+		if err := s.journal(ctx, backup, cmd); err != nil {
+			return fmt.Errorf("%v: %w", cmd.JournalSheetName, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *SyncService) journal(ctx context.Context, backup *domain.Backup, cmd SyncPeriodsCMD) error {
+	from, to := cmd.Periods[0].From, cmd.Periods[len(cmd.Periods)-1].To
+	entries, err := backup.Journal(ctx, from, to)
+	if err != nil {
 		return err
 	}
+	entries = newHabitFilter(cmd.Habits).journal(entries)
 
-	updateCMD := domain.UpdateCMD{
+	_, err = fmt.Fprintf(s.output, "Importing %v notes into %v...\n", len(entries), cmd.JournalSheetName)
+	if err != nil {
+		return err
+	}
+
+	table := domain.JournalTable(entries)
+	table.Title = title(backup, from, to)
+
+	return s.write(ctx, domain.UpdateCMD{
 		Spreadsheet: cmd.Spreadsheet,
-		SheetName:   cmd.SheetName,
-		Habits:      habits,
+		SheetName:   cmd.JournalSheetName,
+		Table:       table,
+		Mode:        cmd.Mode,
+		Create:      cmd.Create,
+		Folder:      cmd.Folder,
+	}, cmd.DryRun)
+}
+
+// write updates the Sheet, or prints what would change on a dry run
+func (s *SyncService) write(ctx context.Context, cmd domain.UpdateCMD, dryRun bool) error {
+	if !dryRun {
+		return s.spreadsheetUpdater.Update(ctx, cmd)
+	}
+
+	existing, err := s.spreadsheetUpdater.Read(ctx, domain.ReadCMD{Spreadsheet: cmd.Spreadsheet, SheetName: cmd.SheetName})
+	if errors.Is(err, domain.ErrSpreadsheetNotFound) && cmd.Create {
+		existing, err = domain.Table{}, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(cmd.Table.Rows) == 0 {
+		_, err = fmt.Fprintf(s.output, "%v: no changes\n", cmd.SheetName)
+		return err
+	}
+	return s.printDiff(cmd.SheetName, domain.Diff(existing, cmd.Table, cmd.Mode))
+}
+
+func (s *SyncService) printDiff(sheet string, d domain.TableDiff) error {
+	if d.Empty() {
+		_, err := fmt.Fprintf(s.output, "%v: no changes\n", sheet)
+		return err
+	}
+
+	summary := fmt.Sprintf("%v: %v added, %v removed, %v changed", sheet, len(d.Added), len(d.Removed), len(d.Changed))
+	if len(d.RemovedColumns) != 0 {
+		summary += fmt.Sprintf(", %v removed columns", len(d.RemovedColumns))
+	}
+	lines := []string{summary}
+	for _, row := range d.Added {
+		lines = append(lines, "  + "+row)
 	}
-	if err := s.spreadsheetUpdater.Update(updateCMD); err != nil {
+	for _, row := range d.Removed {
+		lines = append(lines, "  - "+row)
+	}
+	for _, c := range d.Changed {
+		lines = append(lines, fmt.Sprintf("  ~ %v: %v %q -> %q", c.Row, c.Column, c.Old, c.New))
+	}
+	for _, c := range d.RemovedColumns {
+		lines = append(lines, "  - column "+c)
+	}
+	_, err := fmt.Fprintln(s.output, strings.Join(lines, "\n"))
+	return err
+}
+
+// title describes where the data of a Sheet comes from
+func title(backup *domain.Backup, from, to time.Time) string {
+	return fmt.Sprintf("Imported from %v, from %v to %v", backup.Name, from.Format(dateLayout), to.Format(dateLayout))
+}
+
+// placeholders are the values available on the templates
+func (s *SyncService) placeholders(backup *domain.Backup, period Period) map[string]string {
+	return map[string]string{
+		"title":     title(backup, period.From, period.To),
+		"backup":    backup.Name,
+		"sheet":     period.Name,
+		"from":      period.From.Format(dateLayout),
+		"to":        period.To.Format(dateLayout),
+		"synced_at": s.timeRepository.Now().Format("2006-01-02 15:04"),
+	}
+}
+
+func (s *SyncService) sync(ctx context.Context, backup *domain.Backup, cmd SyncPeriodsCMD, period Period) error {
+	b := tableBuilder{backup: backup, filter: newHabitFilter(cmd.Habits), output: s.output, into: period.Name}
+	table, err := b.build(ctx, cmd.Layout, cmd.Compare, period.From, period.To)
+	if err != nil {
+		return err
+	}
+	table.Title = title(backup, period.From, period.To)
+	table.Placeholders = s.placeholders(backup, period)
+
+	if cmd.Mode == domain.AppendMode {
+		table = domain.HistoryTable(table, s.timeRepository.Now(), period.From, period.To)
+	}
+
+	updateCMD := domain.UpdateCMD{
+		Spreadsheet:           cmd.Spreadsheet,
+		SheetName:             period.Name,
+		Table:                 table,
+		Mode:                  cmd.Mode,
+		Charts:                cmd.Charts,
+		ConditionalFormatting: cmd.ConditionalFormatting,
+		NamedRanges:           cmd.NamedRanges,
+		NamedRangePrefix:      cmd.NamedRangePrefix,
+		Template:              cmd.Template,
+		Create:                cmd.Create,
+		Folder:                cmd.Folder,
+	}
+	if len(cmd.Periods) > 1 {
+		updateCMD.NamedRangePrefix += domain.RangeName(period.Name) + "_"
+	}
+	if err := s.write(ctx, updateCMD, cmd.DryRun); err != nil {
 		return err
 	}
+	if cmd.DryRun {
+		return nil
+	}
 
 	if _, err = fmt.Fprint(s.output, "Habits imported successfully\n"); err != nil {
 		return err
@@ -1,12 +1,17 @@
 package application_test
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"habitsSync/internal/application"
 	"habitsSync/internal/domain"
 	"io"
 	"io/ioutil"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestSyncService_Handle(t *testing.T) {
@@ -79,11 +84,347 @@ func TestSyncService_Handle(t *testing.T) {
 			s := application.NewSyncService(
 				tt.fields.habitsGetter,
 				tt.fields.spreadsheetUpdater,
+				&testTimeRepository{},
 				tt.fields.output,
 			)
-			if err := s.Handle(tt.args.cmd); (err != nil) != tt.wantErr {
+			if err := s.Handle(context.Background(), tt.args.cmd); (err != nil) != tt.wantErr {
 				t.Errorf("Handle() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
+
+func TestSyncService_HandleComparison(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+	endOfDay := func(y int, m time.Month, d int) time.Time {
+		return date(y, m, d).Add(24*time.Hour - time.Nanosecond)
+	}
+	tests := []struct {
+		name     string
+		compare  application.Comparison
+		from, to time.Time
+		wantFrom time.Time
+		wantTo   time.Time
+	}{
+		{
+			name:     "previous quarter",
+			compare:  application.PreviousPeriod,
+			from:     date(2021, 4, 1),
+			to:       endOfDay(2021, 6, 30),
+			wantFrom: date(2021, 1, 1),
+			wantTo:   endOfDay(2021, 3, 31),
+		},
+		{
+			name:     "previous quarter crossing the year",
+			compare:  application.PreviousPeriod,
+			from:     date(2021, 1, 1),
+			to:       endOfDay(2021, 3, 31),
+			wantFrom: date(2020, 10, 1),
+			wantTo:   endOfDay(2020, 12, 31),
+		},
+		{
+			name:     "previous range of the same number of days",
+			compare:  application.PreviousPeriod,
+			from:     date(2021, 1, 10),
+			to:       endOfDay(2021, 1, 19),
+			wantFrom: date(2020, 12, 31),
+			wantTo:   endOfDay(2021, 1, 9),
+		},
+		{
+			name:     "same period last year",
+			compare:  application.SamePeriodLastYear,
+			from:     date(2020, 2, 1),
+			to:       endOfDay(2020, 2, 29),
+			wantFrom: date(2019, 2, 1),
+			wantTo:   endOfDay(2019, 2, 28),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getter := &fakeHabitsGetter{}
+			s := application.NewSyncService(getter, &fakeSpreadsheetUpdater{}, &testTimeRepository{}, ioutil.Discard)
+			err := s.Handle(context.Background(), application.SyncCMD{
+				From:    tt.from,
+				To:      tt.to,
+				Compare: tt.compare,
+			})
+			if err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+			if len(getter.calls) != 2 {
+				t.Fatalf("GetAll() called %v times, want 2", len(getter.calls))
+			}
+			got := getter.calls[1]
+			if !got.From.Equal(tt.wantFrom) || !got.To.Equal(tt.wantTo) {
+				t.Errorf("compared against %v - %v, want %v - %v", got.From, got.To, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestSyncService_HandlePeriods(t *testing.T) {
+	getter := &fakeHabitsGetter{
+		habits: []domain.Habit{{ID: 1, Name: "habit 1", Count: 10}},
+	}
+	updater := &fakeSpreadsheetUpdater{}
+	s := application.NewSyncService(getter, updater, &testTimeRepository{}, ioutil.Discard)
+
+	periods, err := application.NewDatesService(&testTimeRepository{}).Periods(application.PeriodsCMD{
+		Every: "quarter",
+	})
+	if err != nil {
+		t.Fatalf("Periods() error = %v", err)
+	}
+
+	err = s.HandlePeriods(context.Background(), application.SyncPeriodsCMD{
+		Prefix:           "prefix",
+		Spreadsheet:      "spreadsheet",
+		Periods:          periods,
+		NamedRanges:      true,
+		NamedRangePrefix: "okr_",
+	})
+	if err != nil {
+		t.Fatalf("HandlePeriods() error = %v", err)
+	}
+
+	if getter.opened != 1 {
+		t.Errorf("backup opened %v times, want 1", getter.opened)
+	}
+	if len(getter.calls) != len(periods) {
+		t.Errorf("habits queried %v times, want %v", len(getter.calls), len(periods))
+	}
+	if got, want := updater.calls[0].Table.Title, "Imported from prefix, from 2021-01-01 to 2021-03-31"; got != want {
+		t.Errorf("title = %q, want %q", got, want)
+	}
+	sheets := make([]string, 0)
+	for _, c := range updater.calls {
+		sheets = append(sheets, c.SheetName)
+	}
+	want := []string{"Q1 2021", "Q2 2021", "Q3 2021", "Q4 2021"}
+	if !reflect.DeepEqual(sheets, want) {
+		t.Errorf("updated sheets = %v, want %v", sheets, want)
+	}
+	if got, want := updater.calls[1].NamedRangePrefix, "okr_q2_2021_"; got != want {
+		t.Errorf("named range prefix = %q, want %q", got, want)
+	}
+	placeholders := updater.calls[1].Table.Placeholders
+	if placeholders["sheet"] != "Q2 2021" || placeholders["from"] != "2021-04-01" || placeholders["to"] != "2021-06-30" {
+		t.Errorf("placeholders = %v, want the ones of Q2 2021", placeholders)
+	}
+}
+
+func TestSyncPeriodsCMD_Validate(t *testing.T) {
+	q1 := application.Period{Name: "Q1 2021"}
+	tests := []struct {
+		name    string
+		cmd     application.SyncPeriodsCMD
+		wantErr bool
+	}{
+		{name: "periods", cmd: application.SyncPeriodsCMD{Periods: []application.Period{q1, {Name: "Q2 2021"}}, JournalSheetName: "Journal"}},
+		{name: "same Sheet twice", cmd: application.SyncPeriodsCMD{Periods: []application.Period{q1, q1}}, wantErr: true},
+		{name: "journal Sheet", cmd: application.SyncPeriodsCMD{Periods: []application.Period{q1}, JournalSheetName: "Q1 2021"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cmd.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSyncService_HandleWeekdays(t *testing.T) {
+	monday := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		cmd      application.SyncCMD
+		wantRows int
+		wantErr  bool
+	}{
+		{
+			name: "write the weekdays of each habit",
+			cmd: application.SyncCMD{
+				From:   monday,
+				To:     monday.AddDate(0, 0, 7),
+				Layout: application.WeekdaysLayout,
+			},
+			wantRows: 1,
+		},
+		{
+			name: "fail when comparing weekdays",
+			cmd: application.SyncCMD{
+				Layout:  application.WeekdaysLayout,
+				Compare: application.PreviousPeriod,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updater := &fakeSpreadsheetUpdater{}
+			s := application.NewSyncService(&fakeHabitsGetter{
+				repetitions: []domain.Repetition{
+					{HabitID: 1, HabitName: "habit 1", Timestamp: monday},
+				},
+			}, updater, &testTimeRepository{}, ioutil.Discard)
+
+			err := s.Handle(context.Background(), tt.cmd)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Handle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := len(updater.calls[0].Table.Rows); got != tt.wantRows {
+				t.Errorf("Handle() wrote %v rows, want %v", got, tt.wantRows)
+			}
+		})
+	}
+}
+
+func TestParseLayout(t *testing.T) {
+	tests := []struct {
+		arg     string
+		want    application.Layout
+		wantErr bool
+	}{
+		{arg: "", want: application.HabitsLayout},
+		{arg: "habits", want: application.HabitsLayout},
+		{arg: "weekdays", want: application.WeekdaysLayout},
+		{arg: "months", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			got, err := application.ParseLayout(tt.arg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLayout() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLayout() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSyncService_HandleJournal(t *testing.T) {
+	getter := &fakeHabitsGetter{
+		journal: []domain.JournalEntry{{HabitID: 1, HabitName: "habit 1", Note: "note"}},
+	}
+	updater := &fakeSpreadsheetUpdater{}
+	s := application.NewSyncService(getter, updater, &testTimeRepository{}, ioutil.Discard)
+
+	periods, err := application.NewDatesService(&testTimeRepository{}).Periods(application.PeriodsCMD{
+		Every: "month",
+	})
+	if err != nil {
+		t.Fatalf("Periods() error = %v", err)
+	}
+
+	err = s.HandlePeriods(context.Background(), application.SyncPeriodsCMD{
+		Periods:          periods,
+		JournalSheetName: "Journal",
+	})
+	if err != nil {
+		t.Fatalf("HandlePeriods() error = %v", err)
+	}
+
+	last := updater.calls[len(updater.calls)-1]
+	if last.SheetName != "Journal" || len(last.Table.Rows) != 1 {
+		t.Errorf("journal written on %v with %v rows, want Journal with 1 row", last.SheetName, len(last.Table.Rows))
+	}
+	query := getter.calls[len(getter.calls)-1]
+	if !query.From.Equal(periods[0].From) || !query.To.Equal(periods[11].To) {
+		t.Errorf("journal read from %v to %v, want the whole year", query.From, query.To)
+	}
+}
+
+func TestParseWriteMode(t *testing.T) {
+	tests := []struct {
+		arg     string
+		want    domain.WriteMode
+		wantErr bool
+	}{
+		{arg: "", want: domain.OverwriteMode},
+		{arg: "overwrite", want: domain.OverwriteMode},
+		{arg: "merge", want: domain.MergeMode},
+		{arg: "append", want: domain.AppendMode},
+		{arg: "upsert", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			got, err := application.ParseWriteMode(tt.arg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseWriteMode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseWriteMode() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSyncService_HandleAppend(t *testing.T) {
+	updater := &fakeSpreadsheetUpdater{}
+	s := application.NewSyncService(&fakeHabitsGetter{
+		habits:  []domain.Habit{{ID: 1, Name: "habit 1", Count: 10}},
+		journal: []domain.JournalEntry{{HabitID: 1, HabitName: "habit 1", Note: "note"}},
+	}, updater, &testTimeRepository{}, ioutil.Discard)
+
+	err := s.Handle(context.Background(), application.SyncCMD{
+		Mode:             domain.AppendMode,
+		JournalSheetName: "Journal",
+	})
+	if err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+
+	history, journal := updater.calls[0].Table, updater.calls[1].Table
+	if history.Columns[0].Name != "Date" || history.Rows[0][0] != time.Date(2021, 1, 5, 0, 0, 0, 0, time.UTC) {
+		t.Errorf("Handle() appended %v, want the rows prefixed with the sync date", history.Rows)
+	}
+	if journal.Columns[0].Name != "Date" || len(journal.Columns) != 4 {
+		t.Errorf("Handle() appended the journal with columns %v, want them as they are", journal.Columns)
+	}
+}
+
+func TestSyncService_HandleDryRun(t *testing.T) {
+	getter := &fakeHabitsGetter{
+		habits: []domain.Habit{{ID: 1, Name: "run", Count: 7, Color: -1}, {ID: 3, Name: "meditate", Count: 1, Color: -1}},
+	}
+	updater := &fakeSpreadsheetUpdater{
+		existing: domain.Table{
+			Columns: []domain.Column{{Name: "ID"}, {Name: "Name"}, {Name: "Count"}, {Name: "Owner"}},
+			Rows:    [][]interface{}{{1.0, "run", 5.0, "Anna"}, {2.0, "read", 3.0}, {"", "", 8.0, "total"}},
+		},
+	}
+	out := &bytes.Buffer{}
+	s := application.NewSyncService(getter, updater, &testTimeRepository{}, out)
+
+	err := s.Handle(context.Background(), application.SyncCMD{
+		Spreadsheet: "spreadsheet",
+		SheetName:   "Import",
+		DryRun:      true,
+	})
+	if err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+
+	if len(updater.calls) != 0 {
+		t.Errorf("Handle() updated %v sheets on a dry run", len(updater.calls))
+	}
+	if len(updater.reads) != 1 || updater.reads[0].SheetName != "Import" {
+		t.Errorf("Handle() read %v, want Import", updater.reads)
+	}
+	want := `Import: 1 added, 2 removed, 1 changed, 1 removed columns
+  + 3 meditate
+  - 2 read
+  - 8 total
+  ~ 1 run: Count "5" -> "7"
+  - column Owner
+`
+	if got := out.String(); !strings.HasSuffix(got, want) {
+		t.Errorf("Handle() printed %q, want it to end with %q", got, want)
+	}
+}
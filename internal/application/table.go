@@ -0,0 +1,76 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"habitsSync/internal/domain"
+	"io"
+	"time"
+)
+
+// tableBuilder builds the table of a period of the backup, the one the
+// SyncService writes on the Sheet and the ReportService prints
+type tableBuilder struct {
+	backup *domain.Backup
+	filter habitFilter
+	// output is told what is imported into the Sheet named into
+	output io.Writer
+	into   string
+}
+
+func (b tableBuilder) build(ctx context.Context, layout Layout, c Comparison, from, to time.Time) (domain.Table, error) {
+	if layout == WeekdaysLayout {
+		return b.weekdays(ctx, from, to)
+	}
+	return b.habits(ctx, c, from, to)
+}
+
+func (b tableBuilder) habits(ctx context.Context, c Comparison, from, to time.Time) (domain.Table, error) {
+	habits, err := b.backup.AllHabits(ctx, from, to)
+	if err != nil {
+		return domain.Table{}, err
+	}
+	habits = b.filter.habits(habits)
+
+	if _, err = fmt.Fprintf(b.output, "Importing %v habits into %v...\n", len(habits), b.into); err != nil {
+		return domain.Table{}, err
+	}
+
+	if c == NoComparison {
+		return domain.HabitsTable(habits), nil
+	}
+	return b.compare(ctx, c, from, to, habits)
+}
+
+func (b tableBuilder) weekdays(ctx context.Context, from, to time.Time) (domain.Table, error) {
+	repetitions, err := b.backup.Repetitions(ctx, from, to)
+	if err != nil {
+		return domain.Table{}, err
+	}
+	repetitions = b.filter.repetitions(repetitions)
+
+	report := domain.Weekdays(repetitions, from, to)
+	_, err = fmt.Fprintf(b.output, "Importing weekdays of %v habits into %v...\n", len(report.Habits), b.into)
+	if err != nil {
+		return domain.Table{}, err
+	}
+
+	return domain.WeekdaysTable(report), nil
+}
+
+func (b tableBuilder) compare(ctx context.Context, c Comparison, from, to time.Time, habits []domain.Habit) (domain.Table, error) {
+	previousFrom, previousTo := c.Range(from, to)
+	previous, err := b.backup.AllHabits(ctx, previousFrom, previousTo)
+	if err != nil {
+		return domain.Table{}, err
+	}
+	previous = b.filter.habits(previous)
+
+	_, err = fmt.Fprintf(b.output, "Comparing against %v - %v...\n",
+		previousFrom.Format(dateLayout), previousTo.Format(dateLayout))
+	if err != nil {
+		return domain.Table{}, err
+	}
+
+	return domain.ComparisonTable(domain.Compare(habits, previous)), nil
+}
@@ -0,0 +1,241 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"habitsSync/internal/domain"
+	"io"
+	"strings"
+	"sync"
+)
+
+// DefaultParallelism is the number of spreadsheets of the targets updated at
+// the same time
+const DefaultParallelism = 4
+
+// Target is a Sheet the habits are written on. Empty fields take the value of
+// the SyncCMD
+type Target struct {
+	Spreadsheet string
+	SheetName   string
+	Layout      Layout
+	// Habits are the names of the habits written on the target
+	Habits []string
+}
+
+type SyncTargetsCMD struct {
+	SyncCMD
+	Targets     []Target
+	Parallelism int
+}
+
+// TargetsError are the errors of the targets that failed, the rest of them
+// being updated anyway
+type TargetsError []error
+
+func (e TargetsError) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return fmt.Sprintf("%v targets failed: %v", len(e), strings.Join(messages, "; "))
+}
+
+// targets returns the commands of the targets grouped by spreadsheet, in the
+// order they first appear. Targets writing the same Sheet are rejected
+func (c SyncTargetsCMD) targets() ([][]SyncPeriodsCMD, error) {
+	if len(c.Targets) == 0 {
+		return nil, errors.New("there are no targets to sync")
+	}
+
+	groups := make([][]SyncPeriodsCMD, 0)
+	index := make(map[string]int)
+	sheets := make(map[string]bool)
+	for _, t := range c.Targets {
+		cmd := c.SyncCMD
+		if t.Spreadsheet != "" {
+			cmd.Spreadsheet = t.Spreadsheet
+		}
+		if t.SheetName != "" {
+			cmd.SheetName = t.SheetName
+		}
+		if t.Layout != "" {
+			cmd.Layout = t.Layout
+		}
+		if len(t.Habits) != 0 {
+			cmd.Habits = t.Habits
+		}
+
+		periods := cmd.periods()
+		if err := periods.Validate(); err != nil {
+			return nil, fmt.Errorf("%v: %v: %w", cmd.Spreadsheet, cmd.SheetName, err)
+		}
+		sheet := cmd.Spreadsheet + "\x00" + cmd.SheetName
+		if sheets[sheet] || (cmd.JournalSheetName != "" && cmd.SheetName == cmd.JournalSheetName) {
+			return nil, fmt.Errorf("%v: %v: the Sheet is written by another target", cmd.Spreadsheet, cmd.SheetName)
+		}
+		sheets[sheet] = true
+
+		i, ok := index[cmd.Spreadsheet]
+		if !ok {
+			i = len(groups)
+			index[cmd.Spreadsheet] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], periods)
+	}
+	return groups, nil
+}
+
+// HandleTargets opens the backup once and updates the spreadsheets of the
+// targets concurrently, up to Parallelism at the same time. The targets of a
+// spreadsheet are updated one after the other, so it is created once, and
+// its journal is written once, with the habits of all of them. A failing
+// target does not stop the rest of them, see TargetsError. Once the context
+// is done, the targets not started yet fail with its error
+func (s *SyncService) HandleTargets(ctx context.Context, cmd SyncTargetsCMD) error {
+	groups, err := cmd.targets()
+	if err != nil {
+		return err
+	}
+
+	backup, err := s.habitsGetter.Open(ctx, cmd.Prefix)
+	if err != nil {
+		return err
+	}
+
+	parallelism := cmd.Parallelism
+	if parallelism <= 0 {
+		parallelism = DefaultParallelism
+	}
+	semaphore := make(chan struct{}, parallelism)
+	errs := make([][]error, len(groups))
+	var wg sync.WaitGroup
+	for i := range groups {
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if err := ctx.Err(); err != nil {
+			for _, c := range groups[i] {
+				errs[i] = append(errs[i], fmt.Errorf("%v: %w", c.Spreadsheet, err))
+			}
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			errs[i] = s.handleSpreadsheet(ctx, backup, groups[i])
+		}(i)
+	}
+	wg.Wait()
+
+	failed := make(TargetsError, 0)
+	for _, group := range errs {
+		failed = append(failed, group...)
+	}
+	if len(failed) != 0 {
+		return failed
+	}
+	return nil
+}
+
+// handleSpreadsheet updates the targets of a spreadsheet one by one, and then
+// its journal. Returns the errors of the targets that failed
+func (s *SyncService) handleSpreadsheet(ctx context.Context, backup *domain.Backup, cmds []SyncPeriodsCMD) []error {
+	errs := make([]error, 0)
+	journal := cmds[0]
+	journal.Habits = nil
+	all := false
+	for _, cmd := range cmds {
+		journal.Habits = append(journal.Habits, cmd.Habits...)
+		all = all || len(cmd.Habits) == 0
+
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, fmt.Errorf("%v: %w", cmd.Spreadsheet, err))
+			continue
+		}
+		cmd.JournalSheetName = ""
+		if err := s.handlePeriods(ctx, backup, cmd); err != nil {
+			errs = append(errs, fmt.Errorf("%v: %w", cmd.Spreadsheet, err))
+		}
+	}
+
+	if all {
+		journal.Habits = nil
+	}
+	if journal.JournalSheetName != "" && len(journal.Periods) != 0 && ctx.Err() == nil {
+		if err := s.journal(ctx, backup, journal); err != nil {
+			errs = append(errs, fmt.Errorf("%v: %v: %w", journal.Spreadsheet, journal.JournalSheetName, err))
+		}
+	}
+	return errs
+}
+
+// habitFilter keeps the habits by name. Empty keeps all of them
+type habitFilter map[string]bool
+
+func newHabitFilter(names []string) habitFilter {
+	f := make(habitFilter, len(names))
+	for _, n := range names {
+		f[strings.ToLower(strings.TrimSpace(n))] = true
+	}
+	return f
+}
+
+func (f habitFilter) keep(name string) bool {
+	return len(f) == 0 || f[strings.ToLower(name)]
+}
+
+func (f habitFilter) habits(habits []domain.Habit) []domain.Habit {
+	if len(f) == 0 {
+		return habits
+	}
+	kept := make([]domain.Habit, 0, len(habits))
+	for _, h := range habits {
+		if f.keep(h.Name) {
+			kept = append(kept, h)
+		}
+	}
+	return kept
+}
+
+func (f habitFilter) repetitions(repetitions []domain.Repetition) []domain.Repetition {
+	if len(f) == 0 {
+		return repetitions
+	}
+	kept := make([]domain.Repetition, 0, len(repetitions))
+	for _, r := range repetitions {
+		if f.keep(r.HabitName) {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+func (f habitFilter) journal(entries []domain.JournalEntry) []domain.JournalEntry {
+	if len(f) == 0 {
+		return entries
+	}
+	kept := make([]domain.JournalEntry, 0, len(entries))
+	for _, e := range entries {
+		if f.keep(e.HabitName) {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+// lockedWriter lets the targets write their progress at the same time
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
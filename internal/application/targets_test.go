@@ -0,0 +1,182 @@
+package application_test
+
+import (
+	"context"
+	"errors"
+	"habitsSync/internal/application"
+	"habitsSync/internal/domain"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestSyncService_HandleTargets(t *testing.T) {
+	getter := &fakeHabitsGetter{
+		habits: []domain.Habit{{ID: 1, Name: "Run"}, {ID: 2, Name: "Read"}, {ID: 3, Name: "Meditate"}},
+	}
+	failure := errors.New("quota exceeded")
+	updater := &fakeSpreadsheetUpdater{sheetErrs: map[string]error{"Broken": failure}}
+	s := application.NewSyncService(getter, updater, &testTimeRepository{}, ioutil.Discard)
+
+	err := s.HandleTargets(context.Background(), application.SyncTargetsCMD{
+		SyncCMD: application.SyncCMD{
+			Prefix:      "prefix",
+			From:        time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			To:          time.Date(2021, 3, 31, 0, 0, 0, 0, time.UTC),
+			Spreadsheet: "Team OKRs",
+			SheetName:   "Import",
+		},
+		Targets: []application.Target{
+			{},
+			{Spreadsheet: "Anna OKRs", Habits: []string{"run", " Read"}},
+			{Spreadsheet: "Bob OKRs", SheetName: "Broken"},
+			{Spreadsheet: "Bob OKRs", SheetName: "Weekdays", Layout: application.WeekdaysLayout},
+		},
+		Parallelism: 2,
+	})
+
+	var targetsErr application.TargetsError
+	if !errors.As(err, &targetsErr) || len(targetsErr) != 1 || !errors.Is(targetsErr[0], failure) {
+		t.Fatalf("HandleTargets() error = %v, want only Broken failing", err)
+	}
+	if want := "1 targets failed: Bob OKRs: Broken: quota exceeded"; err.Error() != want {
+		t.Errorf("HandleTargets() error = %q, want %q", err, want)
+	}
+	if getter.opened != 1 {
+		t.Errorf("backup opened %v times, want 1", getter.opened)
+	}
+	if len(updater.calls) != 4 {
+		t.Fatalf("HandleTargets() updated %v sheets, want 4", len(updater.calls))
+	}
+
+	rows := make(map[string]int)
+	for _, c := range updater.calls {
+		rows[c.Spreadsheet+"/"+c.SheetName] = len(c.Table.Rows)
+	}
+	want := map[string]int{"Team OKRs/Import": 3, "Anna OKRs/Import": 2, "Bob OKRs/Broken": 3, "Bob OKRs/Weekdays": 0}
+	for target, n := range want {
+		if rows[target] != n {
+			t.Errorf("%v has %v rows, want %v", target, rows[target], n)
+		}
+	}
+	var layouts []string
+	for _, c := range updater.calls {
+		layouts = append(layouts, c.Table.Columns[2].Name)
+	}
+	sort.Strings(layouts)
+	if layouts[0] != "Count" || layouts[3] != "Mon" {
+		t.Errorf("HandleTargets() wrote the columns %v, want the weekdays layout on one target only", layouts)
+	}
+}
+
+func TestSyncService_HandleTargets_invalid(t *testing.T) {
+	getter := &fakeHabitsGetter{}
+	s := application.NewSyncService(getter, &fakeSpreadsheetUpdater{}, &testTimeRepository{}, ioutil.Discard)
+
+	err := s.HandleTargets(context.Background(), application.SyncTargetsCMD{
+		SyncCMD: application.SyncCMD{Spreadsheet: "Team OKRs", SheetName: "Import", Compare: application.PreviousPeriod},
+		Targets: []application.Target{{}, {Layout: application.WeekdaysLayout}},
+	})
+	if err == nil {
+		t.Fatal("HandleTargets() error = nil, want weekdays with comparison to fail")
+	}
+	if getter.opened != 0 {
+		t.Errorf("backup opened before validating all the targets")
+	}
+}
+
+func TestSyncService_HandleTargets_canceled(t *testing.T) {
+	updater := &fakeSpreadsheetUpdater{}
+	s := application.NewSyncService(&fakeHabitsGetter{}, updater, &testTimeRepository{}, ioutil.Discard)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := s.HandleTargets(ctx, application.SyncTargetsCMD{
+		SyncCMD: application.SyncCMD{Prefix: "prefix", Spreadsheet: "Team OKRs", SheetName: "Import"},
+		Targets: []application.Target{{}, {Spreadsheet: "Anna OKRs"}},
+	})
+
+	var targetsErr application.TargetsError
+	if !errors.As(err, &targetsErr) || len(targetsErr) != 2 || !errors.Is(targetsErr[1], context.Canceled) {
+		t.Fatalf("HandleTargets() error = %v, want both targets canceled", err)
+	}
+	if len(updater.calls) != 0 {
+		t.Errorf("HandleTargets() updated %v sheets after being canceled", len(updater.calls))
+	}
+}
+
+func TestSyncService_HandleTargets_sameSpreadsheet(t *testing.T) {
+	getter := &fakeHabitsGetter{
+		habits: []domain.Habit{{ID: 1, Name: "Run"}, {ID: 2, Name: "Read"}, {ID: 3, Name: "Meditate"}},
+		journal: []domain.JournalEntry{
+			{HabitName: "Run", Note: "tired"}, {HabitName: "Read", Note: "a novel"}, {HabitName: "Meditate", Note: "calm"},
+		},
+	}
+	updater := &fakeSpreadsheetUpdater{}
+	s := application.NewSyncService(getter, updater, &testTimeRepository{}, ioutil.Discard)
+
+	err := s.HandleTargets(context.Background(), application.SyncTargetsCMD{
+		SyncCMD: application.SyncCMD{
+			Prefix:           "prefix",
+			Spreadsheet:      "Team OKRs",
+			SheetName:        "Import",
+			JournalSheetName: "Journal",
+			Create:           true,
+		},
+		Targets: []application.Target{
+			{SheetName: "Anna", Habits: []string{"run"}},
+			{Spreadsheet: "Bob OKRs"},
+			{SheetName: "Bob", Habits: []string{"read"}},
+		},
+		Parallelism: 2,
+	})
+	if err != nil {
+		t.Fatalf("HandleTargets() error = %v", err)
+	}
+
+	var team []string
+	journals := make(map[string]int)
+	for _, c := range updater.calls {
+		if c.Spreadsheet == "Team OKRs" {
+			team = append(team, c.SheetName)
+		}
+		if c.SheetName == "Journal" {
+			journals[c.Spreadsheet] = len(c.Table.Rows)
+		}
+	}
+	if want := []string{"Anna", "Bob", "Journal"}; !reflect.DeepEqual(team, want) {
+		t.Errorf("HandleTargets() wrote %v on Team OKRs, want %v one after the other", team, want)
+	}
+	if want := map[string]int{"Team OKRs": 2, "Bob OKRs": 3}; !reflect.DeepEqual(journals, want) {
+		t.Errorf("HandleTargets() wrote the journals with %v notes, want once per spreadsheet with %v", journals, want)
+	}
+}
+
+func TestSyncService_HandleTargets_duplicated(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets []application.Target
+	}{
+		{name: "same sheet", targets: []application.Target{{Spreadsheet: "Team OKRs"}, {SheetName: "Import"}}},
+		{name: "journal", targets: []application.Target{{}, {SheetName: "Journal"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getter := &fakeHabitsGetter{}
+			s := application.NewSyncService(getter, &fakeSpreadsheetUpdater{}, &testTimeRepository{}, ioutil.Discard)
+
+			err := s.HandleTargets(context.Background(), application.SyncTargetsCMD{
+				SyncCMD: application.SyncCMD{Spreadsheet: "Team OKRs", SheetName: "Import", JournalSheetName: "Journal"},
+				Targets: tt.targets,
+			})
+			if err == nil {
+				t.Fatal("HandleTargets() error = nil, want the Sheet written twice to fail")
+			}
+			if getter.opened != 0 {
+				t.Errorf("backup opened before validating all the targets")
+			}
+		})
+	}
+}
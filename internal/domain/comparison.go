@@ -0,0 +1,65 @@
+package domain
+
+import "sort"
+
+// HabitComparison holds the count of a habit for the current period and for
+// the period it is compared against
+type HabitComparison struct {
+	Habit
+	Previous int
+}
+
+// Statuses of a habit against the period it is compared to
+const (
+	OnTrack = "On track"
+	Behind  = "Behind"
+)
+
+func (c HabitComparison) Delta() int {
+	return c.Count - c.Previous
+}
+
+// Change returns the relative change (0.25 means +25%) from the previous
+// period. It is undefined when there were no repetitions on the previous period
+func (c HabitComparison) Change() (float64, bool) {
+	if c.Previous == 0 {
+		return 0, false
+	}
+	return float64(c.Delta()) / float64(c.Previous), true
+}
+
+// Status tells if the habit is done at least as much as on the previous period
+func (c HabitComparison) Status() string {
+	if c.Count < c.Previous {
+		return Behind
+	}
+	return OnTrack
+}
+
+// Compare matches the habits of two periods by ID. Habits present in only one
+// of the periods are compared against a count of zero, and those only in the
+// previous period are added at the end following the Loop order
+func Compare(current, previous []Habit) []HabitComparison {
+	comparisons := make([]HabitComparison, 0, len(current))
+	index := make(map[int]int, len(current))
+	for _, h := range current {
+		index[h.ID] = len(comparisons)
+		comparisons = append(comparisons, HabitComparison{Habit: h})
+	}
+
+	missing := make([]HabitComparison, 0)
+	for _, h := range previous {
+		if i, ok := index[h.ID]; ok {
+			comparisons[i].Previous = h.Count
+			continue
+		}
+		previousCount := h.Count
+		h.Count = 0
+		missing = append(missing, HabitComparison{Habit: h, Previous: previousCount})
+	}
+	sort.SliceStable(missing, func(i, j int) bool {
+		return missing[i].Position < missing[j].Position
+	})
+
+	return append(comparisons, missing...)
+}
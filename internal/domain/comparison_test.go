@@ -0,0 +1,51 @@
+package domain_test
+
+import (
+	"habitsSync/internal/domain"
+	"reflect"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	current := []domain.Habit{
+		{ID: 1, Name: "run", Count: 15},
+		{ID: 2, Name: "read", Count: 3},
+	}
+	previous := []domain.Habit{
+		{ID: 4, Name: "walk", Position: 2, Count: 1},
+		{ID: 3, Name: "meditate", Position: 1, Count: 4},
+		{ID: 1, Name: "run", Count: 10},
+	}
+
+	got := domain.Compare(current, previous)
+	want := []domain.HabitComparison{
+		{Habit: domain.Habit{ID: 1, Name: "run", Count: 15}, Previous: 10},
+		{Habit: domain.Habit{ID: 2, Name: "read", Count: 3}, Previous: 0},
+		{Habit: domain.Habit{ID: 3, Name: "meditate", Position: 1, Count: 0}, Previous: 4},
+		{Habit: domain.Habit{ID: 4, Name: "walk", Position: 2, Count: 0}, Previous: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Compare() got = %v, want %v", got, want)
+	}
+}
+
+func TestComparisonTable(t *testing.T) {
+	table := domain.ComparisonTable([]domain.HabitComparison{
+		{Habit: domain.Habit{ID: 1, Name: "run", Color: 0, Count: 15}, Previous: 10},
+		{Habit: domain.Habit{ID: 2, Name: "read", Color: 100, Count: 3}, Previous: 0},
+		{Habit: domain.Habit{ID: 3, Name: "walk", Color: 100, Count: 1}, Previous: 2},
+	})
+
+	red := domain.Color{R: 0xD3, G: 0x2F, B: 0x2F}
+	want := [][]interface{}{
+		{1, domain.Cell{Value: "run", Background: red}, 15, 10, 5, 0.5, domain.OnTrack},
+		{2, "read", 3, 0, 3, "", domain.OnTrack},
+		{3, "walk", 1, 2, -1, -0.5, domain.Behind},
+	}
+	if !reflect.DeepEqual(table.Rows, want) {
+		t.Errorf("ComparisonTable() rows = %v, want %v", table.Rows, want)
+	}
+	if len(table.Columns) != len(want[0]) {
+		t.Errorf("ComparisonTable() has %v columns, want %v", len(table.Columns), len(want[0]))
+	}
+}
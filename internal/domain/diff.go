@@ -0,0 +1,187 @@
+package domain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TableDiff is what writing a Table would change on a Sheet
+type TableDiff struct {
+	Added   []string
+	Removed []string
+	Changed []CellChange
+	// RemovedColumns are the columns of the Sheet not in the Table, which are
+	// only lost when the Sheet is overwritten
+	RemovedColumns []string
+}
+
+// CellChange is a value of a row that would be replaced
+type CellChange struct {
+	Row    string
+	Column string
+	Old    string
+	New    string
+}
+
+func (d TableDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0 && len(d.RemovedColumns) == 0
+}
+
+// Diff compares the table with the existing one, read from a Sheet, matching
+// their rows by the key of the table and their columns by name. Overwriting
+// replaces the whole Sheet, so the columns and the rows without a key of the
+// user are removed too. Merging keeps them, and appending never removes or
+// changes rows
+func Diff(existing Table, table Table, mode WriteMode) TableDiff {
+	columns := make([]int, len(table.Columns))
+	for i, c := range table.Columns {
+		columns[i] = existing.column(c.Name)
+	}
+	key := table.KeyColumns()
+	existingKey := make([]int, 0, len(key))
+	for _, k := range key {
+		existingKey = append(existingKey, columns[k])
+	}
+
+	rows := make(map[string][]interface{}, len(existing.Rows))
+	order := make([]string, 0, len(existing.Rows))
+	unkeyed := make([]string, 0)
+	for _, row := range existing.Rows {
+		k := rowKey(row, existingKey)
+		if k == "" {
+			// Rows without a key belong to the user
+			if s := describeValues(row); s != "" {
+				unkeyed = append(unkeyed, s)
+			}
+			continue
+		}
+		if _, ok := rows[k]; !ok {
+			order = append(order, k)
+		}
+		rows[k] = row
+	}
+
+	d := TableDiff{}
+	seen := make(map[string]bool, len(table.Rows))
+	for _, row := range table.Rows {
+		k := rowKey(row, key)
+		seen[k] = true
+		old, ok := rows[k]
+		if !ok {
+			d.Added = append(d.Added, table.describe(row))
+			continue
+		}
+		if mode == AppendMode {
+			continue
+		}
+		for i, c := range table.Columns {
+			before, after := "", diffString(row[i])
+			if columns[i] >= 0 && columns[i] < len(old) {
+				before = diffString(old[columns[i]])
+			}
+			if before != after {
+				d.Changed = append(d.Changed, CellChange{Row: table.describe(row), Column: c.Name, Old: before, New: after})
+			}
+		}
+	}
+
+	if mode != AppendMode {
+		for _, k := range order {
+			if !seen[k] {
+				d.Removed = append(d.Removed, existing.describe(rows[k]))
+			}
+		}
+	}
+	if mode == "" || mode == OverwriteMode {
+		d.Removed = append(d.Removed, unkeyed...)
+		for _, c := range existing.Columns {
+			if c.Name != "" && table.column(c.Name) < 0 {
+				d.RemovedColumns = append(d.RemovedColumns, c.Name)
+			}
+		}
+	}
+
+	return d
+}
+
+// column is the index of the column with the name, or -1
+func (t Table) column(name string) int {
+	for i, c := range t.Columns {
+		if c.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// describe names a row to people: its key, and its name if it has one
+func (t Table) describe(row []interface{}) string {
+	parts := make([]string, 0)
+	columns := t.KeyColumns()
+	if name := t.column("Name"); name >= 0 {
+		columns = append(columns[:len(columns):len(columns)], name)
+	}
+	for _, c := range columns {
+		if c >= 0 && c < len(row) {
+			if s := diffString(row[c]); s != "" {
+				parts = append(parts, s)
+			}
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// describeValues names a row without a key by all of its values
+func describeValues(row []interface{}) string {
+	parts := make([]string, 0, len(row))
+	for _, v := range row {
+		if s := diffString(v); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// rowKey identifies a row by the values of the key columns. Empty when all of
+// them are
+func rowKey(row []interface{}, key []int) string {
+	parts := make([]string, 0, len(key))
+	empty := true
+	for _, c := range key {
+		s := ""
+		if c >= 0 && c < len(row) {
+			s = diffString(row[c])
+		}
+		empty = empty && s == ""
+		parts = append(parts, s)
+	}
+	if empty {
+		return ""
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// diffString writes a value the way it is read from a Sheet: numbers as such
+// and dates as text
+func diffString(v interface{}) string {
+	switch v := Value(v).(type) {
+	case nil:
+		return ""
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		if h, m, s := v.Clock(); h == 0 && m == 0 && s == 0 {
+			return v.Format("2006-01-02")
+		}
+		return v.Format("2006-01-02 15:04:05")
+	case string:
+		return v
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,85 @@
+package domain_test
+
+import (
+	"habitsSync/internal/domain"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	existing := domain.Table{
+		Columns: []domain.Column{{Name: "ID"}, {Name: "Name"}, {Name: "Owner"}, {Name: "Count"}},
+		Rows: [][]interface{}{
+			{1.0, "run", "Anna", 5.0},
+			{2.0, "read", "Bob", 3.0},
+			{"", "", "total", 8.0},
+		},
+	}
+	table := domain.HabitsTable([]domain.Habit{
+		{ID: 1, Name: "run", Count: 7, Color: -1},
+		{ID: 3, Name: "meditate", Count: 1, Color: 5},
+	})
+
+	tests := []struct {
+		name string
+		mode domain.WriteMode
+		want domain.TableDiff
+	}{
+		{
+			name: "overwrite",
+			mode: domain.OverwriteMode,
+			want: domain.TableDiff{
+				Added:          []string{"3 meditate"},
+				Removed:        []string{"2 read", "total 8"},
+				Changed:        []domain.CellChange{{Row: "1 run", Column: "Count", Old: "5", New: "7"}},
+				RemovedColumns: []string{"Owner"},
+			},
+		},
+		{
+			name: "merge",
+			mode: domain.MergeMode,
+			want: domain.TableDiff{
+				Added:   []string{"3 meditate"},
+				Removed: []string{"2 read"},
+				Changed: []domain.CellChange{{Row: "1 run", Column: "Count", Old: "5", New: "7"}},
+			},
+		},
+		{
+			name: "append",
+			mode: domain.AppendMode,
+			want: domain.TableDiff{Added: []string{"3 meditate"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := domain.Diff(existing, table, tt.mode); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiff_dates(t *testing.T) {
+	day := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	existing := domain.Table{
+		Columns: []domain.Column{{Name: "Date"}, {Name: "Habit"}, {Name: "Value"}, {Name: "Note"}},
+		Rows:    [][]interface{}{{"2021-03-01", "run", 1.0, "tired"}},
+	}
+	table := domain.JournalTable([]domain.JournalEntry{
+		{Date: day, HabitName: "run", Value: 1, Note: "tired"},
+		{Date: day, HabitName: "water", Value: 1.5, Note: "hot day"},
+	})
+
+	want := domain.TableDiff{Added: []string{"2021-03-01 water"}}
+	if got := domain.Diff(existing, table, domain.OverwriteMode); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDiff_emptySheet(t *testing.T) {
+	table := domain.HabitsTable([]domain.Habit{{ID: 1, Name: "run", Count: 7}})
+	if got := domain.Diff(domain.Table{}, table, domain.OverwriteMode); len(got.Added) != 1 || len(got.Changed) != 0 {
+		t.Errorf("Diff() = %+v, want the habit added", got)
+	}
+}
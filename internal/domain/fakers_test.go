@@ -1,6 +1,7 @@
 package domain_test
 
 import (
+	"context"
 	"habitsSync/internal/domain"
 	"time"
 )
@@ -10,16 +11,40 @@ type fakeDriveRepo struct {
 	err             error
 	errDownload     error
 	payloadDownload []byte
+	folders         []domain.File
+	spreadsheets    []domain.File
+	// created records the spreadsheet created, when set
+	created *createCall
 }
 
-func (f fakeDriveRepo) ListByPrefix(contains string) ([]domain.File, error) {
+type createCall struct {
+	name   string
+	folder string
+}
+
+func (f fakeDriveRepo) ListByPrefix(ctx context.Context, contains string) ([]domain.File, error) {
 	return f.listResult, f.err
 }
 
-func (f fakeDriveRepo) Download(id string) ([]byte, error) {
+func (f fakeDriveRepo) Download(ctx context.Context, id string) ([]byte, error) {
 	return f.payloadDownload, f.errDownload
 }
 
+func (f fakeDriveRepo) ListFolders(ctx context.Context, name string) ([]domain.File, error) {
+	return f.folders, f.err
+}
+
+func (f fakeDriveRepo) ListSpreadsheets(ctx context.Context, name string) ([]domain.File, error) {
+	return f.spreadsheets, f.err
+}
+
+func (f fakeDriveRepo) CreateSpreadsheet(ctx context.Context, name string, folder string) (domain.File, error) {
+	if f.created != nil {
+		*f.created = createCall{name: name, folder: folder}
+	}
+	return domain.File{ID: "created", Name: name}, nil
+}
+
 type fakeFileRepo struct {
 	exists     bool
 	errOnStore error
@@ -43,23 +68,55 @@ func (f fakeStorageMaker) Make(name string) (domain.Storage, error) {
 }
 
 type fakeStorage struct {
-	stats []domain.Habit
-	err   error
+	stats       []domain.Habit
+	repetitions []domain.Repetition
+	err         error
 }
 
-func (f fakeStorage) AllHabits(from, to time.Time) ([]domain.Habit, error) {
+func (f fakeStorage) AllHabits(ctx context.Context, from, to time.Time) ([]domain.Habit, error) {
 	return f.stats, f.err
 }
 
+func (f fakeStorage) Journal(ctx context.Context, from, to time.Time) ([]domain.JournalEntry, error) {
+	return nil, f.err
+}
+
+func (f fakeStorage) Repetitions(ctx context.Context, from, to time.Time) ([]domain.Repetition, error) {
+	return f.repetitions, f.err
+}
+
 type fakeSheetRepo struct {
 	createErr error
 	updateErr error
+	mergeErr  error
+	merged    bool
+	appended  bool
+	updated   domain.Table
+	template  domain.Template
+	existing  domain.Table
 }
 
-func (f *fakeSheetRepo) CreateSheet(id string, name string) error {
+func (f *fakeSheetRepo) CreateSheet(ctx context.Context, id string, name string, template domain.Template) error {
+	f.template = template
 	return f.createErr
 }
 
-func (f *fakeSheetRepo) UpdateSheet(id string, name string, stats []domain.Habit) error {
+func (f *fakeSheetRepo) UpdateSheet(ctx context.Context, id string, name string, table domain.Table) error {
+	f.updated = table
 	return f.updateErr
 }
+
+func (f *fakeSheetRepo) MergeSheet(ctx context.Context, id string, name string, table domain.Table) error {
+	f.merged = true
+	f.updated = table
+	return f.mergeErr
+}
+
+func (f *fakeSheetRepo) AppendSheet(ctx context.Context, id string, name string, table domain.Table) error {
+	f.appended = true
+	return nil
+}
+
+func (f *fakeSheetRepo) ReadSheet(ctx context.Context, id string, name string) (domain.Table, error) {
+	return f.existing, nil
+}
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
@@ -39,24 +40,52 @@ func (c *GetAllCMD) Validate() error {
 	return nil
 }
 
-func (h *Habits) GetAll(cmd GetAllCMD) ([]Habit, error) {
+func (h *Habits) GetAll(ctx context.Context, cmd GetAllCMD) ([]Habit, error) {
 	if err := cmd.Validate(); err != nil {
 		return nil, err
 	}
 
-	files, err := h.driveRepo.ListByPrefix(cmd.Prefix)
+	backup, err := h.Open(ctx, cmd.Prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return backup.AllHabits(ctx, cmd.From, cmd.To)
+}
+
+// Backup is the latest Loop Habits backup, ready to be queried as many times
+// as needed without downloading it again
+type Backup struct {
+	Name string
+	Storage
+}
+
+// Backups returns the backups with the prefix, the one Open uses first
+func (h *Habits) Backups(ctx context.Context, prefix string) ([]File, error) {
+	if prefix == "" {
+		return nil, errors.New("prefix cannot be empty")
+	}
+	return h.driveRepo.ListByPrefix(ctx, prefix)
+}
+
+func (h *Habits) Open(ctx context.Context, prefix string) (*Backup, error) {
+	if prefix == "" {
+		return nil, errors.New("prefix cannot be empty")
+	}
+
+	files, err := h.driveRepo.ListByPrefix(ctx, prefix)
 	if err != nil {
 		return nil, err
 	}
 
 	if len(files) == 0 {
-		return nil, fmt.Errorf("no backup found with prefix '%v'", cmd.Prefix)
+		return nil, fmt.Errorf("no backup found with prefix '%v'", prefix)
 	}
 
 	file := files[0]
 
 	if !h.fileRepo.Exists(file.Name) {
-		if err := h.download(file); err != nil {
+		if err := h.download(ctx, file); err != nil {
 			return nil, err
 		}
 	}
@@ -65,11 +94,11 @@ func (h *Habits) GetAll(cmd GetAllCMD) ([]Habit, error) {
 		return nil, err
 	}
 
-	return storage.AllHabits(cmd.From, cmd.To)
+	return &Backup{Name: file.Name, Storage: storage}, nil
 }
 
-func (h *Habits) download(res File) error {
-	db, err := h.driveRepo.Download(res.ID)
+func (h *Habits) download(ctx context.Context, res File) error {
+	db, err := h.driveRepo.Download(ctx, res.ID)
 	if err != nil {
 		return err
 	}
@@ -1,6 +1,7 @@
 package domain_test
 
 import (
+	"context"
 	"errors"
 	"habitsSync/internal/domain"
 	"reflect"
@@ -231,7 +232,7 @@ func TestHabits_GetAll(t *testing.T) {
 				tt.fields.storageMaker,
 				tt.fields.driveRepo,
 			)
-			got, err := h.GetAll(tt.args.cmd)
+			got, err := h.GetAll(context.Background(), tt.args.cmd)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetAll() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -250,3 +251,82 @@ func validCMD() domain.GetAllCMD {
 		Prefix: "prefix",
 	}
 }
+
+func TestHabits_Open(t *testing.T) {
+	storage := fakeStorage{}
+	tests := []struct {
+		name     string
+		prefix   string
+		fileRepo domain.FileRepository
+		driveRep domain.DriveRepository
+		want     *domain.Backup
+		wantErr  bool
+	}{
+		{
+			name:    "fail on empty prefix",
+			prefix:  "",
+			wantErr: true,
+		},
+		{
+			name:   "fail when no backup is found",
+			prefix: "prefix",
+			driveRep: fakeDriveRepo{
+				listResult: make([]domain.File, 0),
+			},
+			wantErr: true,
+		},
+		{
+			name:     "open the first backup found",
+			prefix:   "prefix",
+			fileRepo: fakeFileRepo{exists: true},
+			driveRep: fakeDriveRepo{
+				listResult: []domain.File{
+					{ID: "1", Name: "first"},
+					{ID: "2", Name: "second"},
+				},
+			},
+			want: &domain.Backup{Name: "first", Storage: storage},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := domain.NewHabits(
+				tt.fileRepo,
+				fakeStorageMaker{storage: storage},
+				tt.driveRep,
+			)
+			got, err := h.Open(context.Background(), tt.prefix)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Open() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Open() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHabits_Backups(t *testing.T) {
+	files := []domain.File{{ID: "1", Name: "first"}, {ID: "2", Name: "second"}}
+	tests := []struct {
+		name    string
+		prefix  string
+		want    []domain.File
+		wantErr bool
+	}{
+		{name: "fail on empty prefix", wantErr: true},
+		{name: "list the backups", prefix: "prefix", want: files},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := domain.NewHabits(fakeFileRepo{}, fakeStorageMaker{}, fakeDriveRepo{listResult: files})
+			got, err := h.Backups(context.Background(), tt.prefix)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Backups() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Backups() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
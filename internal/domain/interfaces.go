@@ -1,12 +1,21 @@
 package domain
 
 import (
+	"context"
 	"time"
 )
 
 type DriveRepository interface {
-	ListByPrefix(contains string) ([]File, error)
-	Download(id string) ([]byte, error)
+	ListByPrefix(ctx context.Context, contains string) ([]File, error)
+	Download(ctx context.Context, id string) ([]byte, error)
+	// ListFolders returns the folders with exactly that name
+	ListFolders(ctx context.Context, name string) ([]File, error)
+	// ListSpreadsheets returns the spreadsheets with exactly that name, out of
+	// the trash
+	ListSpreadsheets(ctx context.Context, name string) ([]File, error)
+	// CreateSpreadsheet creates an empty spreadsheet inside the folder, or on
+	// the root of the Drive when the folder is empty
+	CreateSpreadsheet(ctx context.Context, name string, folder string) (File, error)
 }
 
 type FileRepository interface {
@@ -19,10 +28,19 @@ type StorageMaker interface {
 }
 
 type Storage interface {
-	AllHabits(from, to time.Time) ([]Habit, error)
+	AllHabits(ctx context.Context, from, to time.Time) ([]Habit, error)
+	Repetitions(ctx context.Context, from, to time.Time) ([]Repetition, error)
+	Journal(ctx context.Context, from, to time.Time) ([]JournalEntry, error)
 }
 
 type SheetsRepository interface {
-	CreateSheet(id string, name string) error
-	UpdateSheet(id string, name string, stats []Habit) error
+	// CreateSheet adds the Sheet if it does not exist yet, as a copy of the
+	// template when it has a SheetName
+	CreateSheet(ctx context.Context, id string, name string, template Template) error
+	UpdateSheet(ctx context.Context, id string, name string, table Table) error
+	MergeSheet(ctx context.Context, id string, name string, table Table) error
+	AppendSheet(ctx context.Context, id string, name string, table Table) error
+	// ReadSheet returns the table on the Sheet, its header as Columns. Empty if
+	// the Sheet does not exist
+	ReadSheet(ctx context.Context, id string, name string) (Table, error)
 }
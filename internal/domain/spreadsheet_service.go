@@ -1,8 +1,10 @@
 package domain
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"strings"
 )
 
 type Spreadsheet struct {
@@ -19,10 +21,42 @@ func NewSpreadsheet(
 	}
 }
 
+// ErrSpreadsheetNotFound is returned when no spreadsheet has the given name
+var ErrSpreadsheetNotFound = errors.New("spreadsheet not found")
+
+// WriteMode tells how the Table is written on a Sheet that already has content
+type WriteMode string
+
+const (
+	// OverwriteMode replaces the content of the Sheet
+	OverwriteMode WriteMode = "overwrite"
+	// MergeMode updates the rows matching the key of the Table, appends the new
+	// ones and marks the missing ones, leaving any other column untouched
+	MergeMode WriteMode = "merge"
+	// AppendMode adds the rows of the Table after the content of the Sheet,
+	// skipping the ones whose key is already there
+	AppendMode WriteMode = "append"
+)
+
 type UpdateCMD struct {
 	Spreadsheet string
 	SheetName   string
-	Habits      []Habit
+	Table       Table
+	Mode        WriteMode
+	// Charts and ConditionalFormatting add the ones of the Table to the Sheet,
+	// replacing the ones of the previous import
+	Charts                bool
+	ConditionalFormatting bool
+	// NamedRanges keeps the named ranges of the Table pointing to its cells,
+	// with their names prefixed by NamedRangePrefix
+	NamedRanges      bool
+	NamedRangePrefix string
+	// Template is copied when the Sheet does not exist. The Spreadsheet of the
+	// template is a name, as the one of the command
+	Template Template
+	// Create the spreadsheet when it does not exist, inside the Folder if any
+	Create bool
+	Folder string
 }
 
 func (c *UpdateCMD) Validate() error {
@@ -32,40 +66,107 @@ func (c *UpdateCMD) Validate() error {
 	if c.SheetName == "" {
 		return errors.New("sheet name cannot be empty")
 	}
+	switch c.Mode {
+	case "", OverwriteMode, MergeMode, AppendMode:
+	default:
+		return fmt.Errorf("unknown write mode %q", c.Mode)
+	}
+	if c.Folder != "" && !c.Create {
+		return errors.New("the folder is only used to create the spreadsheet")
+	}
+	if c.Template.Spreadsheet != "" && c.Template.SheetName == "" {
+		return errors.New("the template needs a sheet name")
+	}
+	if c.Template.SheetName != "" && c.Mode != "" && c.Mode != OverwriteMode {
+		return fmt.Errorf("templates are only available on the %v mode", OverwriteMode)
+	}
+	if c.NamedRangePrefix != "" && !strings.EqualFold(RangeName(c.NamedRangePrefix), c.NamedRangePrefix) {
+		return fmt.Errorf("invalid named range prefix %q: use only letters, digits and _, not starting by a digit", c.NamedRangePrefix)
+	}
 	return nil
 }
 
-func (s *Spreadsheet) Update(cmd UpdateCMD) error {
+func (s *Spreadsheet) Update(ctx context.Context, cmd UpdateCMD) error {
 	if err := cmd.Validate(); err != nil {
 		return err
 	}
 
-	if len(cmd.Habits) == 0 {
+	if len(cmd.Table.Rows) == 0 {
 		return nil // Nothing to update
 	}
 
-	spreadsheetID, err := s.findSpreadsheet(cmd.Spreadsheet)
+	var spreadsheetID string
+	var err error
+	if cmd.Create {
+		spreadsheetID, err = s.findOrCreateSpreadsheet(ctx, cmd.Spreadsheet, cmd.Folder)
+	} else {
+		spreadsheetID, err = s.findSpreadsheet(ctx, cmd.Spreadsheet)
+	}
 	if err != nil {
 		return err
 	}
 
-	if err := s.sheetsRepo.CreateSheet(spreadsheetID, cmd.SheetName); err != nil {
-		return err
+	template := cmd.Template
+	if template.Spreadsheet != "" {
+		if template.Spreadsheet, err = s.findSpreadsheet(ctx, template.Spreadsheet); err != nil {
+			return fmt.Errorf("template: %w", err)
+		}
 	}
-	if err := s.sheetsRepo.UpdateSheet(spreadsheetID, cmd.SheetName, cmd.Habits); err != nil {
+
+	if err := s.sheetsRepo.CreateSheet(ctx, spreadsheetID, cmd.SheetName, template); err != nil {
 		return err
 	}
+	return s.write(ctx, spreadsheetID, cmd)
+}
 
-	return nil
+type ReadCMD struct {
+	Spreadsheet string
+	SheetName   string
 }
 
-func (s *Spreadsheet) findSpreadsheet(spreadsheet string) (string, error) {
-	res, err := s.driveRepo.ListByPrefix(spreadsheet)
+// Read returns the table on the Sheet
+func (s *Spreadsheet) Read(ctx context.Context, cmd ReadCMD) (Table, error) {
+	spreadsheetID, err := s.findSpreadsheet(ctx, cmd.Spreadsheet)
+	if err != nil {
+		return Table{}, err
+	}
+	return s.sheetsRepo.ReadSheet(ctx, spreadsheetID, cmd.SheetName)
+}
+
+func (s *Spreadsheet) write(ctx context.Context, spreadsheetID string, cmd UpdateCMD) error {
+	if !cmd.Charts {
+		cmd.Table.Chart = nil
+	}
+	if !cmd.ConditionalFormatting {
+		cmd.Table = cmd.Table.Plain()
+	}
+	if cmd.NamedRanges {
+		cmd.Table.RangePrefix = cmd.NamedRangePrefix
+		if cmd.Mode == MergeMode {
+			// The rows no longer imported stay on the Sheet, so the ranges of
+			// the whole columns would count them
+			cmd.Table = cmd.Table.WithoutColumnRanges()
+		}
+	} else {
+		cmd.Table = cmd.Table.WithoutNamedRanges()
+	}
+
+	switch cmd.Mode {
+	case MergeMode:
+		return s.sheetsRepo.MergeSheet(ctx, spreadsheetID, cmd.SheetName, cmd.Table)
+	case AppendMode:
+		return s.sheetsRepo.AppendSheet(ctx, spreadsheetID, cmd.SheetName, cmd.Table)
+	}
+	return s.sheetsRepo.UpdateSheet(ctx, spreadsheetID, cmd.SheetName, cmd.Table)
+}
+
+func (s *Spreadsheet) findSpreadsheet(ctx context.Context, spreadsheet string) (string, error) {
+	res, err := s.driveRepo.ListByPrefix(ctx, spreadsheet)
 	if err != nil {
 		return "", err
 	}
 	if len(res) == 0 {
-		return "", fmt.Errorf("spreadsheet not found under the name of '%v'", spreadsheet)
+		return "", fmt.Errorf("%w under the name of '%v'", ErrSpreadsheetNotFound, spreadsheet)
 	} else if len(res) > 1 {
 		// TODO: We're searching by prefix so we could check if there is an exact match instead of aborting
 		return "", fmt.Errorf("multiple spreadsheets found with same name: %v. Aborting", spreadsheet)
@@ -73,3 +174,41 @@ func (s *Spreadsheet) findSpreadsheet(spreadsheet string) (string, error) {
 
 	return res[0].ID, nil
 }
+
+// findOrCreateSpreadsheet looks for the spreadsheet by its exact name, as
+// the ones only containing it, or in the trash, are not the one to create
+func (s *Spreadsheet) findOrCreateSpreadsheet(ctx context.Context, name string, folder string) (string, error) {
+	res, err := s.driveRepo.ListSpreadsheets(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	switch len(res) {
+	case 0:
+		return s.createSpreadsheet(ctx, name, folder)
+	case 1:
+		return res[0].ID, nil
+	}
+	return "", fmt.Errorf("multiple spreadsheets found with same name: %v. Aborting", name)
+}
+
+func (s *Spreadsheet) createSpreadsheet(ctx context.Context, name string, folder string) (string, error) {
+	folderID := ""
+	if folder != "" {
+		folders, err := s.driveRepo.ListFolders(ctx, folder)
+		if err != nil {
+			return "", err
+		}
+		if len(folders) == 0 {
+			return "", fmt.Errorf("folder not found under the name of '%v'", folder)
+		} else if len(folders) > 1 {
+			return "", fmt.Errorf("multiple folders found with same name: %v. Aborting", folder)
+		}
+		folderID = folders[0].ID
+	}
+
+	file, err := s.driveRepo.CreateSpreadsheet(ctx, name, folderID)
+	if err != nil {
+		return "", fmt.Errorf("unable to create spreadsheet %v: %w", name, err)
+	}
+	return file.ID, nil
+}
@@ -1,8 +1,10 @@
 package domain_test
 
 import (
+	"context"
 	"errors"
 	"habitsSync/internal/domain"
+	"reflect"
 	"testing"
 )
 
@@ -112,6 +114,21 @@ func TestSpreadsheet_Update(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "fail when merging Sheet with Habits and error",
+			fields: fields{
+				driveRepo: fakeDriveRepo{
+					listResult: make([]domain.File, 1),
+				},
+				sheetsRepo: &fakeSheetRepo{
+					mergeErr: errors.New("fake merge error"),
+				},
+			},
+			args: args{
+				cmd: validMergeCMD(),
+			},
+			wantErr: true,
+		},
 		{
 			name: "fail when updating Sheet with Habits and error",
 			fields: fields{
@@ -135,7 +152,7 @@ func TestSpreadsheet_Update(t *testing.T) {
 				tt.fields.driveRepo,
 				tt.fields.sheetsRepo,
 			)
-			if err := s.Update(tt.args.cmd); (err != nil) != tt.wantErr {
+			if err := s.Update(context.Background(), tt.args.cmd); (err != nil) != tt.wantErr {
 				t.Errorf("Update() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -146,7 +163,11 @@ func TestUpdateCMD_Validate(t *testing.T) {
 	type fields struct {
 		Spreadsheet string
 		SheetName   string
-		Habits      []domain.Habit
+		Table       domain.Table
+		Mode        domain.WriteMode
+		Template    domain.Template
+		Prefix      string
+		Folder      string
 	}
 	tests := []struct {
 		name    string
@@ -169,12 +190,68 @@ func TestUpdateCMD_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "fail on unknown write mode",
+			fields: fields{
+				Spreadsheet: "spreadsheet",
+				SheetName:   "sheet name",
+				Mode:        "upsert",
+			},
+			wantErr: true,
+		},
+		{
+			name: "fail on template spreadsheet without sheet name",
+			fields: fields{
+				Spreadsheet: "spreadsheet",
+				SheetName:   "sheet name",
+				Template:    domain.Template{Spreadsheet: "templates"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "fail on template when merging",
+			fields: fields{
+				Spreadsheet: "spreadsheet",
+				SheetName:   "sheet name",
+				Mode:        domain.MergeMode,
+				Template:    domain.Template{SheetName: "template"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "fail on invalid named range prefix",
+			fields: fields{
+				Spreadsheet: "spreadsheet",
+				SheetName:   "sheet name",
+				Prefix:      "okr-",
+			},
+			wantErr: true,
+		},
+		{
+			name: "fail on folder without creating the spreadsheet",
+			fields: fields{
+				Spreadsheet: "spreadsheet",
+				SheetName:   "sheet name",
+				Folder:      "OKRs",
+			},
+			wantErr: true,
+		},
 		{
 			name: "valid command",
 			fields: fields{
 				Spreadsheet: "spreadsheet",
 				SheetName:   "sheet name",
-				Habits:      nil,
+				Table:       domain.Table{},
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid command with template",
+			fields: fields{
+				Spreadsheet: "spreadsheet",
+				SheetName:   "sheet name",
+				Template:    domain.Template{Spreadsheet: "templates", SheetName: "template"},
+				Prefix:      "OKR_",
 			},
 			wantErr: false,
 		},
@@ -182,9 +259,13 @@ func TestUpdateCMD_Validate(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			c := &domain.UpdateCMD{
-				Spreadsheet: tt.fields.Spreadsheet,
-				SheetName:   tt.fields.SheetName,
-				Habits:      tt.fields.Habits,
+				Spreadsheet:      tt.fields.Spreadsheet,
+				SheetName:        tt.fields.SheetName,
+				Table:            tt.fields.Table,
+				Mode:             tt.fields.Mode,
+				Template:         tt.fields.Template,
+				NamedRangePrefix: tt.fields.Prefix,
+				Folder:           tt.fields.Folder,
 			}
 			if err := c.Validate(); (err != nil) != tt.wantErr {
 				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
@@ -197,20 +278,164 @@ func validUpdateCMD() domain.UpdateCMD {
 	return domain.UpdateCMD{
 		Spreadsheet: "spreadsheet",
 		SheetName:   "sheetname",
-		Habits: []domain.Habit{{
+		Table: domain.HabitsTable([]domain.Habit{{
 			ID:    1,
 			Name:  "habit1",
 			Count: 10,
-		}},
+		}}),
 	}
 }
 
+func validMergeCMD() domain.UpdateCMD {
+	cmd := validUpdateCMD()
+	cmd.Mode = domain.MergeMode
+	return cmd
+}
+
 func validCMDnoHabits() domain.UpdateCMD {
 	habit := validUpdateCMD()
-	habit.Habits = nil
+	habit.Table = domain.Table{}
 	return habit
 }
 
 func invalidCMD() domain.UpdateCMD {
 	return domain.UpdateCMD{}
 }
+
+func TestSpreadsheet_UpdateModes(t *testing.T) {
+	repo := &fakeSheetRepo{}
+	s := domain.NewSpreadsheet(fakeDriveRepo{listResult: make([]domain.File, 1)}, repo)
+
+	merge := validMergeCMD()
+	merge.NamedRanges = true
+	if err := s.Update(context.Background(), merge); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if !repo.merged {
+		t.Error("Update() did not merge the Sheet")
+	}
+	want := []domain.NamedRange{{Name: "habit_1_count", Column: 2, Row: 0}}
+	if got := repo.updated.NamedRanges(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Update() merged the named ranges %v, want only the ones of the rows %v", got, want)
+	}
+
+	cmd := validUpdateCMD()
+	cmd.Mode = domain.AppendMode
+	if err := s.Update(context.Background(), cmd); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if !repo.appended {
+		t.Error("Update() did not append to the Sheet")
+	}
+}
+
+func TestSpreadsheet_UpdateDecorations(t *testing.T) {
+	table := domain.ComparisonTable([]domain.HabitComparison{{Habit: domain.Habit{ID: 1, Name: "run", Color: -1}}})
+	tests := []struct {
+		name        string
+		charts      bool
+		conditional bool
+	}{
+		{name: "all", charts: true, conditional: true},
+		{name: "no charts", conditional: true},
+		{name: "no conditional formatting", charts: true},
+		{name: "none"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeSheetRepo{}
+			s := domain.NewSpreadsheet(fakeDriveRepo{listResult: make([]domain.File, 1)}, repo)
+			cmd := validUpdateCMD()
+			cmd.Table = table
+			cmd.Charts = tt.charts
+			cmd.ConditionalFormatting = tt.conditional
+
+			if err := s.Update(context.Background(), cmd); err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			if got := repo.updated.Chart != nil; got != tt.charts {
+				t.Errorf("Update() wrote a chart = %v, want %v", got, tt.charts)
+			}
+			if got := repo.updated.Columns[4].Highlight != domain.NoHighlight; got != tt.conditional {
+				t.Errorf("Update() highlighted Delta = %v, want %v", got, tt.conditional)
+			}
+		})
+	}
+	if table.Columns[4].Highlight == domain.NoHighlight {
+		t.Error("Update() changed the columns of the table")
+	}
+}
+
+func TestSpreadsheet_UpdateTemplate(t *testing.T) {
+	repo := &fakeSheetRepo{}
+	s := domain.NewSpreadsheet(fakeDriveRepo{listResult: []domain.File{{ID: "abc"}}}, repo)
+	cmd := validUpdateCMD()
+	cmd.Template = domain.Template{Spreadsheet: "templates", SheetName: "template"}
+
+	if err := s.Update(context.Background(), cmd); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if want := (domain.Template{Spreadsheet: "abc", SheetName: "template"}); repo.template != want {
+		t.Errorf("Update() created the sheet from %v, want %v", repo.template, want)
+	}
+}
+
+func TestSpreadsheet_UpdateCreate(t *testing.T) {
+	tests := []struct {
+		name    string
+		create  bool
+		folder  string
+		folders []domain.File
+		// found are the files containing the name, and exact the spreadsheets
+		// named exactly like it
+		found   []domain.File
+		exact   []domain.File
+		want    createCall
+		wantErr bool
+	}{
+		{name: "fail when the spreadsheet does not exist", wantErr: true},
+		{name: "create on the root", create: true, want: createCall{name: "spreadsheet"}},
+		{
+			name:   "create when only other names contain it",
+			create: true,
+			found:  []domain.File{{ID: "s1", Name: "spreadsheet (old)"}, {ID: "s2", Name: "old spreadsheet"}},
+			want:   createCall{name: "spreadsheet"},
+		},
+		{name: "use the one with the name", create: true, exact: []domain.File{{ID: "s1", Name: "spreadsheet"}}},
+		{name: "fail when several have the name", create: true, exact: make([]domain.File, 2), wantErr: true},
+		{
+			name:    "create on a folder",
+			create:  true,
+			folder:  "OKRs",
+			folders: []domain.File{{ID: "f1", Name: "OKRs"}},
+			want:    createCall{name: "spreadsheet", folder: "f1"},
+		},
+		{name: "fail when the folder does not exist", create: true, folder: "OKRs", wantErr: true},
+		{
+			name:    "fail when several folders have the name",
+			create:  true,
+			folder:  "OKRs",
+			folders: make([]domain.File, 2),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created := &createCall{}
+			repo := &fakeSheetRepo{}
+			drive := fakeDriveRepo{listResult: tt.found, spreadsheets: tt.exact, folders: tt.folders, created: created}
+			s := domain.NewSpreadsheet(drive, repo)
+			cmd := validUpdateCMD()
+			cmd.Create = tt.create
+			cmd.Folder = tt.folder
+
+			err := s.Update(context.Background(), cmd)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Update() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if *created != tt.want {
+				t.Errorf("Update() created %+v, want %+v", *created, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+func HabitsTable(habits []Habit) Table {
+	t := Table{
+		Columns: []Column{
+			{Name: "ID"},
+			{Name: "Name", Range: "habits_names"},
+			{Name: "Count", Range: "habits_counts", RowRange: "count"},
+			{Name: "Question"}, {Name: "Description"},
+		},
+		Rows:      make([][]interface{}, 0, len(habits)),
+		RowRanges: make([]string, 0, len(habits)),
+		Chart: &Chart{
+			Title:  "Count per habit",
+			Type:   BarChart,
+			Domain: 1,
+			Series: []int{2},
+		},
+	}
+	for _, h := range habits {
+		t.Rows = append(t.Rows, []interface{}{h.ID, habitName(h), h.Count, h.Question, h.Description})
+		t.RowRanges = append(t.RowRanges, habitRange(h))
+	}
+	return t
+}
+
+// habitName paints the name of the habit with its Loop color
+func habitName(h Habit) interface{} {
+	if c, ok := h.RGB(); ok {
+		return Cell{Value: h.Name, Background: c}
+	}
+	return h.Name
+}
+
+// habitRange names the cells of the habit on the named ranges
+func habitRange(h Habit) string {
+	return fmt.Sprintf("habit_%v", h.ID)
+}
+
+// rowRangeName matches the names given by habitRange to the cells of a habit,
+// with the RowRange of any of the columns of the tables
+var rowRangeName = regexp.MustCompile(`^habit_[0-9]+_(count|previous|delta)$`)
+
+// ComparisonTable writes the delta, the percentage change and whether it is on
+// track next to each habit. The change is left empty when it cannot be computed
+func ComparisonTable(comparisons []HabitComparison) Table {
+	t := Table{
+		Columns: []Column{
+			{Name: "ID"},
+			{Name: "Name", Range: "habits_names"},
+			{Name: "Count", Range: "habits_counts", RowRange: "count"},
+			{Name: "Previous", Range: "habits_previous", RowRange: "previous"},
+			{Name: "Delta", Highlight: SignHighlight, Range: "habits_deltas", RowRange: "delta"},
+			{Name: "Change", Format: PercentFormat, Highlight: SignHighlight},
+			{Name: "Status", Highlight: StatusHighlight},
+		},
+		Rows:      make([][]interface{}, 0, len(comparisons)),
+		RowRanges: make([]string, 0, len(comparisons)),
+		Chart: &Chart{
+			Title:  "Count per habit",
+			Type:   BarChart,
+			Domain: 1,
+			Series: []int{2, 3},
+		},
+	}
+	for _, c := range comparisons {
+		var change interface{} = ""
+		if ch, ok := c.Change(); ok {
+			change = ch
+		}
+		t.Rows = append(t.Rows, []interface{}{c.ID, habitName(c.Habit), c.Count, c.Previous, c.Delta(), change, c.Status()})
+		t.RowRanges = append(t.RowRanges, habitRange(c.Habit))
+	}
+	return t
+}
+
+func JournalTable(entries []JournalEntry) Table {
+	t := Table{
+		Columns: []Column{{Name: "Date", Format: DateFormat}, {Name: "Habit"}, {Name: "Value"}, {Name: "Note"}},
+		Rows:    make([][]interface{}, 0, len(entries)),
+		Key:     []int{0, 1},
+	}
+	for _, e := range entries {
+		t.Rows = append(t.Rows, []interface{}{e.Date, e.HabitName, e.Value, e.Note})
+	}
+	return t
+}
+
+// HistoryTable prefixes each row of the table with the day and time of the sync
+// and the imported range, so the rows of repeated syncs can be appended one
+// after the other. Syncing the same range on the same day gives the same keys
+func HistoryTable(table Table, syncedAt, from, to time.Time) Table {
+	prefix := []interface{}{day(syncedAt), syncedAt, day(from), day(to)}
+	h := Table{
+		Title: table.Title,
+		Columns: []Column{
+			{Name: "Date", Format: DateFormat}, {Name: "Synced at", Format: DateTimeFormat},
+			{Name: "From", Format: DateFormat}, {Name: "To", Format: DateFormat},
+		},
+		Rows: make([][]interface{}, 0, len(table.Rows)),
+		Key:  []int{0, 2, 3},
+	}
+	h.Columns = append(h.Columns, table.WithoutNamedRanges().Columns...)
+	for _, k := range table.KeyColumns() {
+		h.Key = append(h.Key, len(prefix)+k)
+	}
+	for _, row := range table.Rows {
+		r := make([]interface{}, 0, len(prefix)+len(row))
+		r = append(r, prefix...)
+		h.Rows = append(h.Rows, append(r, row...))
+	}
+	return h
+}
+
+// day is the date of t, as written on a Sheet
+func day(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
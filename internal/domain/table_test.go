@@ -0,0 +1,137 @@
+package domain_test
+
+import (
+	"habitsSync/internal/domain"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestJournalTable(t *testing.T) {
+	day := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	table := domain.JournalTable([]domain.JournalEntry{
+		{Date: day, HabitID: 1, HabitName: "run", Value: 1, Note: "tired"},
+		{Date: day, HabitID: 2, HabitName: "water", Value: 1.5, Note: "hot day"},
+	})
+
+	want := [][]interface{}{
+		{day, "run", 1.0, "tired"},
+		{day, "water", 1.5, "hot day"},
+	}
+	if !reflect.DeepEqual(table.Rows, want) {
+		t.Errorf("JournalTable() rows = %v, want %v", table.Rows, want)
+	}
+	if got := table.Header(); !reflect.DeepEqual(got, []interface{}{"Date", "Habit", "Value", "Note"}) {
+		t.Errorf("JournalTable() header = %v", got)
+	}
+}
+
+func TestHabitsTable(t *testing.T) {
+	table := domain.HabitsTable([]domain.Habit{
+		{ID: 1, Name: "run", Question: "Did you run?", Description: "5k", Color: 11, Count: 10},
+	})
+
+	blue := domain.Color{R: 0x19, G: 0x76, B: 0xD2}
+	want := [][]interface{}{
+		{1, domain.Cell{Value: "run", Background: blue}, 10, "Did you run?", "5k"},
+	}
+	if !reflect.DeepEqual(table.Rows, want) {
+		t.Errorf("HabitsTable() rows = %v, want %v", table.Rows, want)
+	}
+	if !blue.Dark() {
+		t.Errorf("Dark() = false, want white text on %v", blue)
+	}
+}
+
+func TestHistoryTable(t *testing.T) {
+	syncedAt := time.Date(2021, 3, 10, 18, 30, 0, 0, time.UTC)
+	from := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2021, 3, 31, 23, 59, 59, 999999999, time.UTC)
+	table := domain.HabitsTable([]domain.Habit{{ID: 1, Name: "run", Color: -1, Count: 10}})
+
+	history := domain.HistoryTable(table, syncedAt, from, to)
+
+	wantRow := []interface{}{
+		time.Date(2021, 3, 10, 0, 0, 0, 0, time.UTC), syncedAt,
+		from, time.Date(2021, 3, 31, 0, 0, 0, 0, time.UTC),
+		1, "run", 10, "", "",
+	}
+	if !reflect.DeepEqual(history.Rows[0], wantRow) {
+		t.Errorf("HistoryTable() row = %v, want %v", history.Rows[0], wantRow)
+	}
+	if !reflect.DeepEqual(history.KeyColumns(), []int{0, 2, 3, 4}) {
+		t.Errorf("HistoryTable() key = %v, want date, range and habit ID", history.KeyColumns())
+	}
+	if len(history.Columns) != len(wantRow) {
+		t.Errorf("HistoryTable() has %v columns, want %v", len(history.Columns), len(wantRow))
+	}
+}
+
+func TestTable_NamedRanges(t *testing.T) {
+	table := domain.HabitsTable([]domain.Habit{{ID: 1, Name: "run"}, {ID: 7, Name: "read"}})
+	table.RangePrefix = "okr_"
+
+	want := []domain.NamedRange{
+		{Name: "okr_habits_names", Column: 1, Row: -1},
+		{Name: "okr_habits_counts", Column: 2, Row: -1},
+		{Name: "okr_habit_1_count", Column: 2, Row: 0},
+		{Name: "okr_habit_7_count", Column: 2, Row: 1},
+	}
+	if got := table.NamedRanges(); !reflect.DeepEqual(got, want) {
+		t.Errorf("NamedRanges() = %v, want %v", got, want)
+	}
+	if !table.IsRowRange("okr_habit_9_count") || table.IsRowRange("okr_habits_names") || table.IsRowRange("habit_9_count") {
+		t.Error("IsRowRange() does not tell the ranges of the rows of the table")
+	}
+	if got := table.WithoutNamedRanges().NamedRanges(); len(got) != 0 {
+		t.Errorf("WithoutNamedRanges() = %v, want none", got)
+	}
+}
+
+func TestTable_IsRowRange(t *testing.T) {
+	plain := domain.HabitsTable([]domain.Habit{{ID: 1, Name: "run"}})
+	prefixed := plain
+	prefixed.RangePrefix = "okr_"
+	tests := []struct {
+		name  string
+		table domain.Table
+		want  bool
+	}{
+		{name: "habit_1_count", table: plain, want: true},
+		{name: "habit_12_previous", table: plain, want: true}, // left by -compare
+		{name: "habit_12_delta", table: plain, want: true},
+		{name: "okr_habit_1_count", table: prefixed, want: true},
+		{name: "weekly_count", table: plain},
+		{name: "kr_delta", table: plain},
+		{name: "habit_run_count", table: plain},
+		{name: "habit_1_count_2020", table: plain},
+		{name: "my_habit_1_count", table: plain},
+		{name: "habit_1_count", table: prefixed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.table.IsRowRange(tt.name); got != tt.want {
+				t.Errorf("IsRowRange() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRangeName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "Q1 2021", want: "q1_2021"},
+		{name: "2021-01", want: "_2021_01"},
+		{name: "okr_", want: "okr_"},
+		{name: "", want: "_"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := domain.RangeName(tt.name); got != tt.want {
+				t.Errorf("RangeName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
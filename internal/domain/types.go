@@ -1,12 +1,277 @@
 package domain
 
+import (
+	"strings"
+	"time"
+)
+
 type Habit struct {
-	ID    int
-	Name  string
-	Count int
+	ID          int
+	Name        string
+	Question    string
+	Description string
+	// Color is the index of the color on the Loop palette
+	Color int
+	// Position is the order given by the user on Loop
+	Position int
+	Count    int
+}
+
+// RGB returns the color of the habit as shown by Loop
+func (h Habit) RGB() (Color, bool) {
+	if h.Color < 0 || h.Color >= len(loopPalette) {
+		return Color{}, false
+	}
+	return loopPalette[h.Color], true
+}
+
+// Repetition is a day on which a habit has been done
+type Repetition struct {
+	HabitID   int
+	HabitName string
+	Timestamp time.Time
+}
+
+// JournalEntry is a repetition that has a note written on it
+type JournalEntry struct {
+	Date      time.Time
+	HabitID   int
+	HabitName string
+	// Value is the amount of numerical habits, or 1 for done yes/no habits
+	Value float64
+	Note  string
 }
 
 type File struct {
 	ID   string
 	Name string
+	// Modified is when the file was last changed. Only set for the backups
+	Modified time.Time
+}
+
+type Color struct {
+	R, G, B uint8
+}
+
+// loopPalette are the colors a habit can have on Loop
+var loopPalette = []Color{
+	{0xD3, 0x2F, 0x2F}, {0xE6, 0x4A, 0x19}, {0xF5, 0x7C, 0x00}, {0xFF, 0x8F, 0x00},
+	{0xF9, 0xA8, 0x25}, {0xAF, 0xB4, 0x2B}, {0x7C, 0xB3, 0x42}, {0x38, 0x8E, 0x3C},
+	{0x00, 0x89, 0x7B}, {0x00, 0xAC, 0xC1}, {0x03, 0x9B, 0xE5}, {0x19, 0x76, 0xD2},
+	{0x30, 0x3F, 0x9F}, {0x5E, 0x35, 0xB1}, {0x8E, 0x24, 0xAA}, {0xD8, 0x1B, 0x60},
+	{0x5D, 0x40, 0x37}, {0x30, 0x30, 0x30}, {0x75, 0x75, 0x75}, {0xAA, 0xAA, 0xAA},
+}
+
+// Dark tells if a white text reads better than a black one on top of the color
+func (c Color) Dark() bool {
+	luminance := 0.299*float64(c.R) + 0.587*float64(c.G) + 0.114*float64(c.B)
+	return luminance < 160
+}
+
+// Cell is a value written on top of a background color
+type Cell struct {
+	Value      interface{}
+	Background Color
+}
+
+// Value returns the value of a Table cell, unwrapping it when it's a Cell
+func Value(v interface{}) interface{} {
+	if c, ok := v.(Cell); ok {
+		return c.Value
+	}
+	return v
+}
+
+// Format tells how the values of a column are shown
+type Format string
+
+const (
+	PlainFormat    Format = ""
+	PercentFormat  Format = "percent"
+	DateFormat     Format = "date"
+	DateTimeFormat Format = "datetime"
+)
+
+// Highlight tells how the values of a column stand out with conditional
+// formatting
+type Highlight string
+
+const (
+	NoHighlight Highlight = ""
+	// ScaleHighlight goes from red on the lowest value to green on the highest
+	ScaleHighlight Highlight = "scale"
+	// SignHighlight is green on positive values and red on negative ones
+	SignHighlight Highlight = "sign"
+	// StatusHighlight is green on habits on track and red on the ones behind
+	StatusHighlight Highlight = "status"
+)
+
+type Column struct {
+	Name      string
+	Format    Format
+	Highlight Highlight
+	// Range is the name of the named range of the whole column, and RowRange
+	// the suffix of the named range of each one of its cells. Empty for none
+	Range    string
+	RowRange string
+}
+
+type ChartType string
+
+const (
+	BarChart  ChartType = "BAR"
+	LineChart ChartType = "LINE"
+)
+
+// Chart plots the Series columns against the Domain column, that labels them.
+// When ByRow, every row is a line plotted against the header instead, and the
+// Series must be the columns right after the Domain
+type Chart struct {
+	Title  string
+	Type   ChartType
+	Domain int
+	Series []int
+	ByRow  bool
+}
+
+// Table is the content written on a Sheet: a header followed by rows of values
+type Table struct {
+	// Title is a line written above the header, describing where the data
+	// comes from
+	Title   string
+	Columns []Column
+	Rows    [][]interface{}
+	// Key are the columns that identify a row, by default the first one
+	Key   []int
+	Chart *Chart
+	// RowRanges name each row on the named ranges of its cells
+	RowRanges []string
+	// RangePrefix is prepended to all the named ranges of the table
+	RangePrefix string
+	// Placeholders are the values of the {{placeholders}} of a template
+	Placeholders map[string]string
+}
+
+// NamedRanges returns the named ranges of the table, by name, of the columns
+// and of the cells of each row
+func (t Table) NamedRanges() []NamedRange {
+	ranges := make([]NamedRange, 0)
+	for i, c := range t.Columns {
+		if c.Range != "" {
+			ranges = append(ranges, NamedRange{Name: t.RangePrefix + c.Range, Column: i, Row: -1})
+		}
+	}
+	for j, row := range t.RowRanges {
+		if row == "" {
+			continue
+		}
+		for i, c := range t.Columns {
+			if c.RowRange != "" {
+				ranges = append(ranges, NamedRange{Name: t.RangePrefix + row + "_" + c.RowRange, Column: i, Row: j})
+			}
+		}
+	}
+	return ranges
+}
+
+// IsRowRange tells if the name belongs to the cell of a row written by an
+// import with the prefix of the table, even of a row or a column that is no
+// longer in it, as after switching the layout
+func (t Table) IsRowRange(name string) bool {
+	return strings.HasPrefix(name, t.RangePrefix) && rowRangeName.MatchString(name[len(t.RangePrefix):])
+}
+
+// WithoutNamedRanges returns the table without named ranges
+func (t Table) WithoutNamedRanges() Table {
+	columns := make([]Column, 0, len(t.Columns))
+	for _, c := range t.Columns {
+		c.Range, c.RowRange = "", ""
+		columns = append(columns, c)
+	}
+	t.Columns = columns
+	t.RowRanges = nil
+	return t
+}
+
+// WithoutColumnRanges returns the table without the named ranges of the whole
+// columns, keeping the ones of the cells of each row
+func (t Table) WithoutColumnRanges() Table {
+	columns := make([]Column, 0, len(t.Columns))
+	for _, c := range t.Columns {
+		c.Range = ""
+		columns = append(columns, c)
+	}
+	t.Columns = columns
+	return t
+}
+
+// TableAnchor is the placeholder of a template where the table is written
+const TableAnchor = "{{table}}"
+
+// Template is a Sheet to start new Sheets with. The table is written at its
+// TableAnchor, and the rest of {{placeholders}} are replaced by their values
+type Template struct {
+	// Spreadsheet of the template. Empty for the one being updated
+	Spreadsheet string
+	SheetName   string
+}
+
+// Render replaces the placeholders of the text by their values. Unknown
+// placeholders are left untouched
+func (t Table) Render(text string) string {
+	for k, v := range t.Placeholders {
+		text = strings.ReplaceAll(text, "{{"+k+"}}", v)
+	}
+	return text
+}
+
+// NamedRange is a Column of the table, or a cell of it when Row is not -1
+type NamedRange struct {
+	Name   string
+	Column int
+	Row    int
+}
+
+// RangeName turns s into a valid name for a named range
+func RangeName(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	name := b.String()
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+// Plain returns the table without conditional formatting
+func (t Table) Plain() Table {
+	columns := make([]Column, 0, len(t.Columns))
+	for _, c := range t.Columns {
+		c.Highlight = NoHighlight
+		columns = append(columns, c)
+	}
+	t.Columns = columns
+	return t
+}
+
+func (t Table) KeyColumns() []int {
+	if len(t.Key) == 0 {
+		return []int{0}
+	}
+	return t.Key
+}
+
+func (t Table) Header() []interface{} {
+	header := make([]interface{}, 0, len(t.Columns))
+	for _, c := range t.Columns {
+		header = append(header, c.Name)
+	}
+	return header
 }
@@ -0,0 +1,98 @@
+package domain
+
+import "time"
+
+// weekdays in the order they are written, starting the week on Monday
+var weekdays = []time.Weekday{
+	time.Monday, time.Tuesday, time.Wednesday, time.Thursday,
+	time.Friday, time.Saturday, time.Sunday,
+}
+
+// HabitWeekdays counts the repetitions of a habit on each day of the week,
+// indexed by time.Weekday
+type HabitWeekdays struct {
+	ID    int
+	Name  string
+	Count [7]int
+}
+
+type WeekdaysReport struct {
+	Habits []HabitWeekdays
+	// Days is the number of Mondays, Tuesdays... on the range, indexed by time.Weekday
+	Days [7]int
+}
+
+// Rate returns the share of the weekdays of the range on which the habit was
+// done. It is undefined when the range does not contain such weekday
+func (r WeekdaysReport) Rate(h HabitWeekdays, d time.Weekday) (float64, bool) {
+	if r.Days[d] == 0 {
+		return 0, false
+	}
+	return float64(h.Count[d]) / float64(r.Days[d]), true
+}
+
+// Weekdays aggregates the repetitions of each habit per day of the week. The
+// habits keep the order in which they first appear on the repetitions
+func Weekdays(repetitions []Repetition, from, to time.Time) WeekdaysReport {
+	report := WeekdaysReport{Habits: make([]HabitWeekdays, 0)}
+
+	y, m, d := from.Date()
+	for day := time.Date(y, m, d, 0, 0, 0, 0, from.Location()); !day.After(to); day = day.AddDate(0, 0, 1) {
+		report.Days[day.Weekday()]++
+	}
+
+	index := make(map[int]int)
+	for _, r := range repetitions {
+		i, ok := index[r.HabitID]
+		if !ok {
+			i = len(report.Habits)
+			index[r.HabitID] = i
+			report.Habits = append(report.Habits, HabitWeekdays{ID: r.HabitID, Name: r.HabitName})
+		}
+		report.Habits[i].Count[r.Timestamp.Weekday()]++
+	}
+
+	return report
+}
+
+// WeekdaysTable writes a habit per row with the count of repetitions from
+// Monday to Sunday followed by the rate of each weekday
+func WeekdaysTable(r WeekdaysReport) Table {
+	t := Table{
+		Columns: []Column{{Name: "ID"}, {Name: "Name"}},
+		Rows:    make([][]interface{}, 0, len(r.Habits)),
+		Chart: &Chart{
+			Title:  "Repetitions per weekday",
+			Type:   LineChart,
+			Domain: 1,
+			ByRow:  true,
+		},
+	}
+	for _, d := range weekdays {
+		t.Chart.Series = append(t.Chart.Series, len(t.Columns))
+		t.Columns = append(t.Columns, Column{Name: d.String()[:3]})
+	}
+	for _, d := range weekdays {
+		t.Columns = append(t.Columns, Column{
+			Name:      d.String()[:3] + " %",
+			Format:    PercentFormat,
+			Highlight: ScaleHighlight,
+		})
+	}
+
+	for _, h := range r.Habits {
+		row := []interface{}{h.ID, h.Name}
+		for _, d := range weekdays {
+			row = append(row, h.Count[d])
+		}
+		for _, d := range weekdays {
+			var rate interface{} = ""
+			if rt, ok := r.Rate(h, d); ok {
+				rate = rt
+			}
+			row = append(row, rate)
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t
+}
@@ -0,0 +1,56 @@
+package domain_test
+
+import (
+	"habitsSync/internal/domain"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWeekdays(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2021, 3, d, 0, 0, 0, 0, time.UTC) // 2021-03-01 is a Monday
+	}
+	from, to := day(1), day(14).Add(24*time.Hour-time.Nanosecond)
+
+	report := domain.Weekdays([]domain.Repetition{
+		{HabitID: 2, HabitName: "run", Timestamp: day(1)},
+		{HabitID: 2, HabitName: "run", Timestamp: day(8)},
+		{HabitID: 1, HabitName: "read", Timestamp: day(7)},
+		{HabitID: 2, HabitName: "run", Timestamp: day(10)},
+	}, from, to)
+
+	wantDays := [7]int{2, 2, 2, 2, 2, 2, 2}
+	if report.Days != wantDays {
+		t.Errorf("Weekdays() days = %v, want %v", report.Days, wantDays)
+	}
+
+	want := []domain.HabitWeekdays{
+		{ID: 2, Name: "run", Count: [7]int{time.Monday: 2, time.Wednesday: 1}},
+		{ID: 1, Name: "read", Count: [7]int{time.Sunday: 1}},
+	}
+	if !reflect.DeepEqual(report.Habits, want) {
+		t.Errorf("Weekdays() habits = %v, want %v", report.Habits, want)
+	}
+
+	table := domain.WeekdaysTable(report)
+	wantRow := []interface{}{2, "run", 2, 0, 1, 0, 0, 0, 0, 1.0, 0.0, 0.5, 0.0, 0.0, 0.0, 0.0}
+	if !reflect.DeepEqual(table.Rows[0], wantRow) {
+		t.Errorf("WeekdaysTable() row = %v, want %v", table.Rows[0], wantRow)
+	}
+	if len(table.Columns) != len(wantRow) {
+		t.Errorf("WeekdaysTable() has %v columns, want %v", len(table.Columns), len(wantRow))
+	}
+}
+
+func TestWeekdaysTable_rateIsEmptyWhenWeekdayIsNotInRange(t *testing.T) {
+	monday := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	report := domain.Weekdays([]domain.Repetition{
+		{HabitID: 1, HabitName: "run", Timestamp: monday},
+	}, monday, monday.Add(24*time.Hour-time.Nanosecond))
+
+	row := domain.WeekdaysTable(report).Rows[0]
+	if row[9] != 1.0 || row[10] != "" {
+		t.Errorf("WeekdaysTable() rates = %v, want Monday 1 and Tuesday empty", row[9:])
+	}
+}
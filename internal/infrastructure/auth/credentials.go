@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"golang.org/x/oauth2/google"
+)
+
+// serviceAccountType is the type of the key files of service accounts
+const serviceAccountType = "service_account"
+
+// Credentials tell how the calls to the Google APIs are authorized
+type Credentials struct {
+	// Path of the credentials file: an OAuth client, or the key of a service
+	// account, told apart by its content
+	Path string
+	// TokenPath is the token of the OAuth client, saved when authorizing
+	TokenPath string
+	// Subject is the user impersonated by the service account, with
+	// domain-wide delegation. Empty to act as the service account itself
+	Subject string
+}
+
+// Provider authorizes the calls of the repositories with the credentials,
+// for the scopes of the features. They all share the same client
+type Provider struct {
+	credentials Credentials
+	features    []Feature
+	once        sync.Once
+	client      *http.Client
+	err         error
+}
+
+// NewProvider authorizes the features, or the DefaultFeatures when none
+func NewProvider(c Credentials, features ...Feature) *Provider {
+	if len(features) == 0 {
+		features = DefaultFeatures
+	}
+	return &Provider{credentials: c, features: features}
+}
+
+// Client returns the authorized client, failing if the token was not granted
+// the scopes of the features. See ErrMissingScope. The context is the one of
+// the refreshes of the token, so it must be the one of the command
+func (p *Provider) Client(ctx context.Context) (*http.Client, error) {
+	p.once.Do(func() {
+		p.client, p.err = p.newClient(ctx)
+	})
+	return p.client, p.err
+}
+
+func (p *Provider) newClient(ctx context.Context) (*http.Client, error) {
+	b, err := ioutil.ReadFile(p.credentials.Path)
+	if err != nil {
+		return nil, err
+	}
+	scopes := Scopes(p.features...)
+
+	if isServiceAccount(b) {
+		config, err := google.JWTConfigFromJSON(b, scopes...)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse service account key file: %v", err)
+		}
+		config.Subject = p.credentials.Subject
+		return config.Client(ctx), nil
+	}
+
+	if p.credentials.Subject != "" {
+		return nil, errors.New("the subject is only used with the key of a service account")
+	}
+	config, err := google.ConfigFromJSON(b, scopes...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse client secret file to config: %v", err)
+	}
+	client, err := tokenClient(ctx, config, p.credentials.TokenPath, p.features)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", p.credentials.TokenPath, err)
+	}
+	return client, nil
+}
+
+func isServiceAccount(credentials []byte) bool {
+	var f struct {
+		Type string `json:"type"`
+	}
+	return json.Unmarshal(credentials, &f) == nil && f.Type == serviceAccountType
+}
@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// serviceAccount writes the key file of a service account using the server
+// as its token endpoint
+func serviceAccount(t *testing.T, dir string, tokenURL string) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	content, err := json.Marshal(map[string]string{
+		"type":           serviceAccountType,
+		"client_email":   "bot@project.iam.gserviceaccount.com",
+		"private_key_id": "key",
+		"private_key":    string(pemKey),
+		"token_uri":      tokenURL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "service-account.json")
+	if err := ioutil.WriteFile(path, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestProvider_Client(t *testing.T) {
+	var claims struct {
+		Issuer  string `json:"iss"`
+		Scope   string `json:"scope"`
+		Subject string `json:"sub"`
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		parts := strings.Split(r.PostForm.Get("assertion"), ".")
+		if len(parts) != 3 {
+			t.Errorf("token asked with %v, want a JWT assertion", r.PostForm)
+			return
+		}
+		payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		_ = json.Unmarshal(payload, &claims)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"access_token": "bot", "token_type": "Bearer", "expires_in": 3600}`)
+	})
+	mux.HandleFunc("/api", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, r.Header.Get("Authorization"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	tokenPath := filepath.Join(dir, "auth.json")
+	user := &oauth2.Token{AccessToken: "user", TokenType: "Bearer", Expiry: time.Now().Add(time.Hour)}
+	if err := saveToken(tokenPath, &storedToken{Token: user}); err != nil {
+		t.Fatal(err)
+	}
+	readOnlyPath := filepath.Join(dir, "read-only.json")
+	if err := saveToken(readOnlyPath, &storedToken{Token: user, Scope: featureScopes[ReadBackups]}); err != nil {
+		t.Fatal(err)
+	}
+	key := serviceAccount(t, dir, server.URL+"/token")
+	client := credentials(t, dir, server.URL+"/token")
+
+	tests := []struct {
+		name        string
+		credentials Credentials
+		want        string
+		wantErr     bool
+	}{
+		{name: "OAuth client", credentials: Credentials{Path: client, TokenPath: tokenPath}, want: "Bearer user"},
+		{name: "OAuth client without token", credentials: Credentials{Path: client, TokenPath: filepath.Join(dir, "missing.json")}, wantErr: true},
+		{name: "OAuth client without the scope", credentials: Credentials{Path: client, TokenPath: readOnlyPath}, wantErr: true},
+		{name: "OAuth client with subject", credentials: Credentials{Path: client, TokenPath: tokenPath, Subject: "anna@example.com"}, wantErr: true},
+		{name: "service account", credentials: Credentials{Path: key}, want: "Bearer bot"},
+		{name: "service account with subject", credentials: Credentials{Path: key, Subject: "anna@example.com"}, want: "Bearer bot"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpClient, err := NewProvider(tt.credentials, WriteSpreadsheets).Client(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Client() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			rsp, err := httpClient.Get(server.URL + "/api")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = rsp.Body.Close() }()
+			got, _ := ioutil.ReadAll(rsp.Body)
+			if string(got) != tt.want {
+				t.Errorf("Client() authorized as %q, want %q", got, tt.want)
+			}
+			if tt.credentials.Path == key {
+				if claims.Issuer != "bot@project.iam.gserviceaccount.com" || claims.Subject != tt.credentials.Subject {
+					t.Errorf("Client() asked a token for %v as %v, want %v as %v",
+						claims.Issuer, claims.Subject, "bot@project.iam.gserviceaccount.com", tt.credentials.Subject)
+				}
+				if claims.Scope != "https://www.googleapis.com/auth/spreadsheets" {
+					t.Errorf("Client() asked the scope %q", claims.Scope)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthRepository_serviceAccount(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	repo := NewAuthRepository(serviceAccount(t, dir, "http://localhost/token"), filepath.Join(dir, "auth.json"))
+	if _, err := repo.GenerateAuthURL(authorization{}); err == nil || !strings.Contains(err.Error(), "share the spreadsheets") {
+		t.Errorf("GenerateAuthURL() error = %v, want to share the spreadsheets instead", err)
+	}
+}
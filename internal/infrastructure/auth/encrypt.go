@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// PassphraseEnv is the environment variable with the passphrase the token
+// files are encrypted with. Without it they are saved as plain JSON
+const PassphraseEnv = "HSYNC_TOKEN_PASSPHRASE"
+
+const encryption = "scrypt-aes-256-gcm"
+
+// ErrPassphrase is returned when an encrypted token file cannot be decrypted
+var ErrPassphrase = errors.New("wrong passphrase")
+
+// encryptedToken is the content of an encrypted token file
+type encryptedToken struct {
+	Encryption string `json:"encryption"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Data       []byte `json:"data"`
+}
+
+func encrypt(plain []byte, passphrase string) (*encryptedToken, error) {
+	e := &encryptedToken{Encryption: encryption, Salt: make([]byte, 16)}
+	if _, err := rand.Read(e.Salt); err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(passphrase, e.Salt)
+	if err != nil {
+		return nil, err
+	}
+	e.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(e.Nonce); err != nil {
+		return nil, err
+	}
+	e.Data = gcm.Seal(nil, e.Nonce, plain, nil)
+	return e, nil
+}
+
+func (e *encryptedToken) decrypt(passphrase string) ([]byte, error) {
+	if e.Encryption != encryption {
+		return nil, fmt.Errorf("unknown encryption %q", e.Encryption)
+	}
+	gcm, err := newGCM(passphrase, e.Salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(e.Nonce) != gcm.NonceSize() {
+		return nil, errors.New("invalid nonce")
+	}
+	plain, err := gcm.Open(nil, e.Nonce, e.Data, nil)
+	if err != nil {
+		return nil, ErrPassphrase
+	}
+	return plain, nil
+}
+
+// newGCM derives the key of the passphrase with the parameters scrypt
+// recommends for interactive logins
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
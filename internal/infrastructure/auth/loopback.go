@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// authorization is an authorization in progress. The State protects the
+// redirect from forged requests and the Verifier the code, see PKCE (RFC 7636)
+type authorization struct {
+	State       string
+	Verifier    string
+	RedirectURL string
+}
+
+func newAuthorization() (authorization, error) {
+	state, err := randomString()
+	if err != nil {
+		return authorization{}, err
+	}
+	verifier, err := randomString()
+	if err != nil {
+		return authorization{}, err
+	}
+	return authorization{State: state, Verifier: verifier}, nil
+}
+
+// challenge is the S256 code challenge of the verifier
+func (a authorization) challenge() string {
+	sum := sha256.Sum256([]byte(a.Verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("unable to generate a random value: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func listenLoopback() (net.Listener, error) {
+	return net.Listen("tcp", "127.0.0.1:0")
+}
+
+// errStateMismatch is a redirect that does not belong to the authorization
+var errStateMismatch = errors.New("the state of the authorization does not match, authorize again")
+
+// receiveCode serves the redirect of the authorization on the listener, and
+// closes it once the redirect arrives. Requests without the state of the
+// authorization are rejected, and the redirect is waited for anyway
+func receiveCode(ctx context.Context, l net.Listener, state string) (string, error) {
+	type result struct {
+		code string
+		err  error
+	}
+	results := make(chan result, 1)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		code, err := codeFromQuery(r.URL.Query(), state)
+		if errors.Is(err, errStateMismatch) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			_, _ = fmt.Fprintln(w, "habitsSync is authorized, you can close this window")
+		}
+		select {
+		case results <- result{code: code, err: err}:
+		default:
+		}
+	})}
+	go func() { _ = srv.Serve(l) }()
+	defer func() {
+		// Let the browser get its page before closing
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdown)
+	}()
+
+	select {
+	case r := <-results:
+		return r.code, r.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// parseCode takes the code from the address of the redirect, or the code
+// itself, pasted by the user
+func parseCode(input string, state string) (string, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return "", errors.New("no authorization code")
+	}
+	if !strings.Contains(input, "?") {
+		return input, nil
+	}
+	u, err := url.Parse(input)
+	if err != nil {
+		return "", fmt.Errorf("invalid address %q: %w", input, err)
+	}
+	return codeFromQuery(u.Query(), state)
+}
+
+func codeFromQuery(q url.Values, state string) (string, error) {
+	if q.Get("state") != state {
+		return "", errStateMismatch
+	}
+	if e := q.Get("error"); e != "" {
+		return "", fmt.Errorf("authorization denied: %v", e)
+	}
+	code := q.Get("code")
+	if code == "" {
+		return "", errors.New("no authorization code")
+	}
+	return code, nil
+}
+
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// ConfigDir is where the credentials and the tokens are kept by default,
+// $XDG_CONFIG_HOME/hsync or ~/.config/hsync on Linux
+func ConfigDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "hsync"), nil
+}
+
+// DefaultPath returns the file with the name in the ConfigDir. Earlier
+// versions kept it on the working directory, so that one is used until it's
+// moved
+func DefaultPath(name string) (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, name)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if _, err := os.Stat(name); err == nil {
+			log.Printf("using %v of the working directory, move it to %v", name, dir)
+			return name, nil
+		}
+	}
+	return path, nil
+}
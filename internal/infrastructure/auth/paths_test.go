@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestDefaultPath(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("only Linux follows $XDG_CONFIG_HOME")
+	}
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Chdir(wd) }()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer func(xdg string) { _ = os.Setenv("XDG_CONFIG_HOME", xdg) }(os.Getenv("XDG_CONFIG_HOME"))
+	config := filepath.Join(dir, "config")
+	_ = os.Setenv("XDG_CONFIG_HOME", config)
+
+	tests := []struct {
+		name  string
+		files []string
+		want  string
+	}{
+		{name: "no file yet", want: filepath.Join(config, "hsync", "auth.json")},
+		{name: "on the working directory", files: []string{"auth.json"}, want: "auth.json"},
+		{name: "on both", files: []string{"auth.json", filepath.Join(config, "hsync", "auth.json")}, want: filepath.Join(config, "hsync", "auth.json")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, f := range tt.files {
+				if err := os.MkdirAll(filepath.Dir(f), 0700); err != nil {
+					t.Fatal(err)
+				}
+				if err := ioutil.WriteFile(f, []byte("{}"), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := DefaultPath("auth.json")
+			if err != nil {
+				t.Fatalf("DefaultPath() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("DefaultPath() = %v, want %v", got, tt.want)
+			}
+			for _, f := range tt.files {
+				if _, err := os.Stat(f); err != nil {
+					t.Errorf("DefaultPath() did not leave %v in place: %v", f, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package auth
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var profileName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ProfileTokenPath returns the token file of a named profile, next to the
+// given one: auth.json becomes auth.work.json for the work profile. The
+// default profile, with no name, keeps the given file
+func ProfileTokenPath(tokenPath, profile string) (string, error) {
+	if profile == "" {
+		return tokenPath, nil
+	}
+	if !profileName.MatchString(profile) {
+		return "", fmt.Errorf("invalid profile %q, use only letters, digits, - and _", profile)
+	}
+	ext := filepath.Ext(tokenPath)
+	return strings.TrimSuffix(tokenPath, ext) + "." + profile + ext, nil
+}
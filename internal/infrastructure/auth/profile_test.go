@@ -0,0 +1,29 @@
+package auth
+
+import "testing"
+
+func TestProfileTokenPath(t *testing.T) {
+	tests := []struct {
+		name      string
+		tokenPath string
+		profile   string
+		want      string
+		wantErr   bool
+	}{
+		{name: "default profile", tokenPath: "auth.json", want: "auth.json"},
+		{name: "named profile", tokenPath: "auth.json", profile: "work", want: "auth.work.json"},
+		{name: "directory", tokenPath: "/home/anna/.hsync/token", profile: "home_2", want: "/home/anna/.hsync/token.home_2"},
+		{name: "invalid profile", tokenPath: "auth.json", profile: "../work", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProfileTokenPath(tt.tokenPath, tt.profile)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ProfileTokenPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ProfileTokenPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,20 +1,17 @@
 package auth
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"os"
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
-	"google.golang.org/api/sheets/v4"
-
-	"golang.org/x/net/context"
-	"google.golang.org/api/drive/v3"
 )
 
+// ReadWriter talks to the user on the terminal
 type ReadWriter struct {
 }
 
@@ -23,64 +20,68 @@ func NewReadWriter() *ReadWriter {
 }
 
 func (i *ReadWriter) Read(p []byte) (n int, err error) {
-	buf := make([]byte, 0)
-	if n, err = fmt.Fscan(os.Stdin, &buf); err != nil {
-		return n, fmt.Errorf("unable to read authorization code %v", err)
-	}
-	return copy(p, buf), io.EOF
+	return os.Stdin.Read(p)
 }
 
-func (i *ReadWriter) Write(url []byte) (n int, err error) {
-	msg := fmt.Sprintf("Go to the following link in your browser then type the "+
-		"authorization code: \n%v\n", string(url))
-	return fmt.Fprint(os.Stdout, msg)
+func (i *ReadWriter) Write(p []byte) (n int, err error) {
+	return os.Stdout.Write(p)
 }
 
 type authRepository struct {
 	credentialsPath string
 	tokenPath       string
+	scopes          []string
+	revokeURL       string
+	aboutURL        string
 }
 
-func NewAuthRepository(credentialsPath, tokenPath string) *authRepository {
+// NewAuthRepository authorizes the scopes of the features, or the ones of the
+// DefaultFeatures when none
+func NewAuthRepository(credentialsPath, tokenPath string, features ...Feature) *authRepository {
+	if len(features) == 0 {
+		features = DefaultFeatures
+	}
 	return &authRepository{
 		credentialsPath: credentialsPath,
 		tokenPath:       tokenPath,
+		scopes:          Scopes(features...),
+		revokeURL:       googleRevokeURL,
+		aboutURL:        driveAboutURL,
 	}
 }
 
-func (ar *authRepository) GenerateAuthURL() (string, error) {
-	config, err := getConfig(ar.credentialsPath)
+func (ar *authRepository) GenerateAuthURL(a authorization) (string, error) {
+	config, err := getConfig(ar.credentialsPath, ar.scopes...)
 	if err != nil {
 		return "", err
 	}
-	return config.AuthCodeURL("state-token", oauth2.AccessTypeOffline), nil
+	config.RedirectURL = a.RedirectURL
+	return config.AuthCodeURL(a.State, oauth2.AccessTypeOffline,
+		oauth2.SetAuthURLParam("code_challenge", a.challenge()),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	), nil
 }
 
-func (ar *authRepository) SaveAuthToken(authCode string) error {
-	config, err := getConfig(ar.credentialsPath)
+func (ar *authRepository) SaveAuthToken(ctx context.Context, a authorization, authCode string) error {
+	config, err := getConfig(ar.credentialsPath, ar.scopes...)
 	if err != nil {
 		return err
 	}
-	tok, err := config.Exchange(context.TODO(), authCode)
+	config.RedirectURL = a.RedirectURL
+	tok, err := config.Exchange(ctx, authCode, oauth2.SetAuthURLParam("code_verifier", a.Verifier))
 	if err != nil {
 		return fmt.Errorf("unable to retrieve token from web %v", err)
 	}
-	return saveToken(ar.tokenPath, tok)
+	return saveToken(ar.tokenPath, newStoredToken(tok, ""))
 }
 
-func getConfig(credentialsPath string) (*oauth2.Config, error) {
+func getConfig(credentialsPath string, scopes ...string) (*oauth2.Config, error) {
 	b, err := ioutil.ReadFile(credentialsPath)
 	if err != nil {
 		return nil, err
 	}
-	return google.ConfigFromJSON(b, drive.DriveMetadataReadonlyScope, drive.DriveReadonlyScope, sheets.SpreadsheetsScope)
-}
-
-func saveToken(path string, token *oauth2.Token) error {
-	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
-	if err != nil {
-		return fmt.Errorf("unable to cache oauth token: %v", err)
+	if isServiceAccount(b) {
+		return nil, errors.New("service accounts need no authorization, share the spreadsheets with the email of the account instead")
 	}
-	defer func() { _ = f.Close() }()
-	return json.NewEncoder(f).Encode(token)
+	return google.ConfigFromJSON(b, scopes...)
 }
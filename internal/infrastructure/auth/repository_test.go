@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// credentials writes a credentials file of a desktop client using the server
+// as its token endpoint
+func credentials(t *testing.T, dir string, tokenURL string) string {
+	path := filepath.Join(dir, "credentials.json")
+	content := fmt.Sprintf(`{"installed": {"client_id": "id", "client_secret": "secret",
+		"auth_uri": "https://accounts.example.com/auth", "token_uri": %q, "redirect_uris": ["http://localhost"]}}`, tokenURL)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestAuthRepository_PKCE(t *testing.T) {
+	var exchanged url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		exchanged = r.PostForm
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"access_token": "access", "refresh_token": "refresh", "token_type": "Bearer", "expires_in": 3600,
+			"scope": "https://www.googleapis.com/auth/spreadsheets"}`)
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	tokenPath := filepath.Join(dir, "auth.json")
+	repo := NewAuthRepository(credentials(t, dir, server.URL), tokenPath)
+
+	a, err := newAuthorization()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.RedirectURL = "http://127.0.0.1:4242"
+
+	authURL, err := repo.GenerateAuthURL(a)
+	if err != nil {
+		t.Fatalf("GenerateAuthURL() error = %v", err)
+	}
+	u, _ := url.Parse(authURL)
+	q := u.Query()
+	if q.Get("state") != a.State || q.Get("code_challenge") != a.challenge() || q.Get("code_challenge_method") != "S256" {
+		t.Errorf("GenerateAuthURL() = %v, want the state and the S256 challenge", authURL)
+	}
+	if q.Get("redirect_uri") != a.RedirectURL {
+		t.Errorf("GenerateAuthURL() redirects to %v, want %v", q.Get("redirect_uri"), a.RedirectURL)
+	}
+
+	if err := repo.SaveAuthToken(context.Background(), a, "abc"); err != nil {
+		t.Fatalf("SaveAuthToken() error = %v", err)
+	}
+	if exchanged.Get("code") != "abc" || exchanged.Get("code_verifier") != a.Verifier || exchanged.Get("redirect_uri") != a.RedirectURL {
+		t.Errorf("SaveAuthToken() exchanged %v, want the code, the verifier and the redirect", exchanged)
+	}
+	tok, err := tokenFromFile(tokenPath)
+	if err != nil || tok.RefreshToken != "refresh" || tok.Scope != "https://www.googleapis.com/auth/spreadsheets" {
+		t.Errorf("SaveAuthToken() saved %+v, %v, want the refresh token and the granted scope", tok, err)
+	}
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/sheets/v4"
+)
+
+// Feature is something the import does, needing its own scope
+type Feature string
+
+const (
+	ReadBackups        Feature = "read the backups"
+	WriteSpreadsheets  Feature = "write the spreadsheets"
+	CreateSpreadsheets Feature = "create spreadsheets"
+)
+
+// DefaultFeatures are the ones of every import
+var DefaultFeatures = []Feature{ReadBackups, WriteSpreadsheets}
+
+var featureScopes = map[Feature]string{
+	ReadBackups:        drive.DriveReadonlyScope,
+	WriteSpreadsheets:  sheets.SpreadsheetsScope,
+	CreateSpreadsheets: drive.DriveFileScope,
+}
+
+// ErrMissingScope is returned when the token was not granted the scope of a
+// feature, because it was authorized without it or the user unchecked it
+var ErrMissingScope = errors.New("missing permission")
+
+// Scopes returns the scopes of the features, once each
+func Scopes(features ...Feature) []string {
+	scopes := make([]string, 0, len(features))
+	seen := make(map[string]bool, len(features))
+	for _, f := range features {
+		if s := featureScopes[f]; s != "" && !seen[s] {
+			seen[s] = true
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
+
+// checkScopes fails if the granted scopes, separated by spaces, miss the
+// scope of any of the features. Tokens saved before the scopes were stored
+// have none and cannot be checked
+func checkScopes(granted string, features []Feature) error {
+	if granted == "" {
+		return nil
+	}
+	has := make(map[string]bool)
+	for _, s := range strings.Fields(granted) {
+		has[s] = true
+	}
+	for _, f := range features {
+		if s := featureScopes[f]; s != "" && !has[s] {
+			hint := ""
+			if f == CreateSpreadsheets {
+				hint = " -create"
+			}
+			return fmt.Errorf("%w: the token cannot %v, authorize again with hsync auth%v", ErrMissingScope, f, hint)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScopes(t *testing.T) {
+	got := Scopes(ReadBackups, WriteSpreadsheets, ReadBackups, CreateSpreadsheets)
+	want := []string{
+		"https://www.googleapis.com/auth/drive.readonly",
+		"https://www.googleapis.com/auth/spreadsheets",
+		"https://www.googleapis.com/auth/drive.file",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Scopes() = %v, want %v", got, want)
+	}
+}
+
+func TestCheckScopes(t *testing.T) {
+	all := strings.Join(Scopes(ReadBackups, WriteSpreadsheets, CreateSpreadsheets), " ")
+	tests := []struct {
+		name     string
+		granted  string
+		features []Feature
+		wantErr  string
+	}{
+		{name: "granted", granted: all, features: []Feature{ReadBackups, CreateSpreadsheets}},
+		{name: "unknown", granted: "", features: []Feature{CreateSpreadsheets}},
+		{
+			name:     "unchecked",
+			granted:  strings.Join(Scopes(ReadBackups), " "),
+			features: DefaultFeatures,
+			wantErr:  "missing permission: the token cannot write the spreadsheets, authorize again with hsync auth",
+		},
+		{
+			name:     "not asked",
+			granted:  strings.Join(Scopes(DefaultFeatures...), " "),
+			features: []Feature{ReadBackups, WriteSpreadsheets, CreateSpreadsheets},
+			wantErr:  "missing permission: the token cannot create spreadsheets, authorize again with hsync auth -create",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkScopes(tt.granted, tt.features)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkScopes() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrMissingScope) || err.Error() != tt.wantErr {
+				t.Errorf("checkScopes() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
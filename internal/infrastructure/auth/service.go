@@ -1,43 +1,145 @@
 package auth
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
+	"net"
+	"strings"
+	"time"
 )
 
 type authRepo interface {
-	GenerateAuthURL() (string, error)
-	SaveAuthToken(string) error
+	GenerateAuthURL(a authorization) (string, error)
+	SaveAuthToken(ctx context.Context, a authorization, code string) error
+	Status(ctx context.Context) (Status, error)
+	Revoke(ctx context.Context) error
 }
 
 // TODO: Move this service to the Service package
 type Service struct {
-	rw       io.ReadWriter
-	authRepo authRepo
+	rw          io.ReadWriter
+	authRepo    authRepo
+	listen      func() (net.Listener, error)
+	openBrowser func(url string) error
 }
 
 func NewService(rw io.ReadWriter, authRepo authRepo) *Service {
 	return &Service{
-		rw:       rw,
-		authRepo: authRepo,
+		rw:          rw,
+		authRepo:    authRepo,
+		listen:      listenLoopback,
+		openBrowser: openBrowser,
 	}
 }
 
-func (s *Service) Handle() error {
-	url, err := s.authRepo.GenerateAuthURL()
+type AuthorizeCMD struct {
+	// Headless asks to paste the address Google redirects to, instead of
+	// receiving it on a local server, for machines without a browser
+	Headless bool
+}
+
+// Handle authorizes on the browser, receiving the code on a local server. It
+// falls back to pasting the code when the server cannot be started
+func (s *Service) Handle(ctx context.Context, cmd AuthorizeCMD) error {
+	a, err := newAuthorization()
+	if err != nil {
+		return err
+	}
+
+	if !cmd.Headless {
+		l, err := s.listen()
+		if err == nil {
+			return s.loopback(ctx, l, a)
+		}
+		if _, err := fmt.Fprintf(s.rw, "Unable to receive the authorization on this machine: %v\n", err); err != nil {
+			return err
+		}
+	}
+	return s.headless(ctx, a)
+}
+
+func (s *Service) loopback(ctx context.Context, l net.Listener, a authorization) error {
+	a.RedirectURL = "http://" + l.Addr().String()
+	url, err := s.authRepo.GenerateAuthURL(a)
 	if err != nil {
+		_ = l.Close()
 		return err
 	}
 
-	if _, err := s.rw.Write([]byte(url)); err != nil {
+	msg := fmt.Sprintf("Authorize habitsSync on your browser. If it does not open, go to the following link:\n%v\n", url)
+	if _, err := fmt.Fprint(s.rw, msg); err != nil {
+		_ = l.Close()
 		return err
 	}
+	_ = s.openBrowser(url) // The link is there anyway
 
-	authCode, err := ioutil.ReadAll(s.rw)
+	code, err := receiveCode(ctx, l, a.State)
 	if err != nil {
+		return err
+	}
+	return s.authRepo.SaveAuthToken(ctx, a, code)
+}
+
+func (s *Service) headless(ctx context.Context, a authorization) error {
+	a.RedirectURL = "http://127.0.0.1"
+	url, err := s.authRepo.GenerateAuthURL(a)
+	if err != nil {
+		return err
+	}
+
+	msg := fmt.Sprintf("Go to the following link in your browser. Once authorized, it goes to a page that does "+
+		"not load: paste its address here:\n%v\n", url)
+	if _, err := fmt.Fprint(s.rw, msg); err != nil {
+		return err
+	}
+
+	line, err := bufio.NewReader(s.rw).ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
 		return fmt.Errorf("unable to read authorization code %v", err)
 	}
+	code, err := parseCode(line, a.State)
+	if err != nil {
+		return err
+	}
+	return s.authRepo.SaveAuthToken(ctx, a, code)
+}
+
+// Status shows the account, the scopes and the expiry of the authorization
+func (s *Service) Status(ctx context.Context) error {
+	st, err := s.authRepo.Status(ctx)
+	if err != nil {
+		return err
+	}
 
-	return s.authRepo.SaveAuthToken(string(authCode))
+	account := st.Account
+	if st.AccountErr != nil {
+		account = fmt.Sprintf("unknown, %v", st.AccountErr)
+	}
+	if st.ServiceAccount {
+		_, err = fmt.Fprintf(s.rw, "Service account: %v\n", account)
+		return err
+	}
+	scopes := "unknown, authorized before they were saved"
+	if len(st.Scopes) != 0 {
+		scopes = strings.Join(st.Scopes, " ")
+	}
+	expiry := "never"
+	if !st.Expiry.IsZero() {
+		expiry = st.Expiry.Local().Format(time.RFC1123)
+	}
+	_, err = fmt.Fprintf(s.rw, "Account: %v\nScopes: %v\nAccess token expiry: %v (refreshed when needed)\n",
+		account, scopes, expiry)
+	return err
+}
+
+// Revoke revokes the authorization and deletes its token
+func (s *Service) Revoke(ctx context.Context) error {
+	if err := s.authRepo.Revoke(ctx); err != nil {
+		return err
+	}
+	_, err := fmt.Fprint(s.rw, "Authorization revoked, the token was deleted\n")
+	return err
 }
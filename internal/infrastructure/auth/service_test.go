@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeAuthRepo struct {
+	authorization authorization
+	code          string
+	status        Status
+	revoked       bool
+}
+
+func (f *fakeAuthRepo) GenerateAuthURL(a authorization) (string, error) {
+	q := url.Values{"state": {a.State}, "redirect_uri": {a.RedirectURL}}
+	return "https://accounts.example.com/auth?" + q.Encode(), nil
+}
+
+func (f *fakeAuthRepo) SaveAuthToken(ctx context.Context, a authorization, code string) error {
+	f.authorization, f.code = a, code
+	return nil
+}
+
+func (f *fakeAuthRepo) Status(ctx context.Context) (Status, error) {
+	return f.status, nil
+}
+
+func (f *fakeAuthRepo) Revoke(ctx context.Context) error {
+	f.revoked = true
+	return nil
+}
+
+type terminal struct {
+	io.Reader
+	io.Writer
+}
+
+func TestService_Handle_loopback(t *testing.T) {
+	tests := []struct {
+		name string
+		// redirects are the requests to the loopback, the last one by Google
+		redirects    func(state string) []url.Values
+		wantStatuses []int
+		wantErr      bool
+	}{
+		{
+			name: "authorized",
+			redirects: func(state string) []url.Values {
+				return []url.Values{{"code": {"abc"}, "state": {state}}}
+			},
+			wantStatuses: []int{http.StatusOK},
+		},
+		{
+			name: "forged state",
+			redirects: func(state string) []url.Values {
+				return []url.Values{
+					{"code": {"forged"}, "state": {"forged"}},
+					{"error": {"access_denied"}},
+					{"code": {"abc"}, "state": {state}},
+				}
+			},
+			wantStatuses: []int{http.StatusBadRequest, http.StatusBadRequest, http.StatusOK},
+		},
+		{
+			name: "denied",
+			redirects: func(state string) []url.Values {
+				return []url.Values{{"error": {"access_denied"}, "state": {state}}}
+			},
+			wantStatuses: []int{http.StatusBadRequest},
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeAuthRepo{}
+			s := NewService(terminal{strings.NewReader(""), &bytes.Buffer{}}, repo)
+			statuses := make(chan []int, 1)
+			s.openBrowser = func(authURL string) error {
+				// The browser follows the redirect while the service waits for it
+				go func() {
+					u, _ := url.Parse(authURL)
+					got := make([]int, 0)
+					for _, q := range tt.redirects(u.Query().Get("state")) {
+						rsp, err := http.Get(u.Query().Get("redirect_uri") + "/?" + q.Encode())
+						if err != nil {
+							t.Errorf("redirect failed: %v", err)
+							break
+						}
+						_ = rsp.Body.Close()
+						got = append(got, rsp.StatusCode)
+					}
+					statuses <- got
+				}()
+				return nil
+			}
+
+			err := s.Handle(context.Background(), AuthorizeCMD{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Handle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := <-statuses; !reflect.DeepEqual(got, tt.wantStatuses) {
+				t.Errorf("loopback answered %v, want %v", got, tt.wantStatuses)
+			}
+			if tt.wantErr {
+				return
+			}
+			if repo.code != "abc" || !strings.HasPrefix(repo.authorization.RedirectURL, "http://127.0.0.1:") {
+				t.Errorf("Handle() saved the code %q redirected to %v", repo.code, repo.authorization.RedirectURL)
+			}
+		})
+	}
+}
+
+func TestService_Handle_headless(t *testing.T) {
+	repo := &fakeAuthRepo{}
+	out := &bytes.Buffer{}
+	s := NewService(terminal{strings.NewReader("abc\n"), out}, repo)
+	s.listen = func() (net.Listener, error) { return nil, errors.New("no network") }
+
+	if err := s.Handle(context.Background(), AuthorizeCMD{}); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	if repo.code != "abc" {
+		t.Errorf("Handle() saved the code %q, want abc", repo.code)
+	}
+	if !strings.Contains(out.String(), "https://accounts.example.com/auth?") {
+		t.Errorf("Handle() printed %q, want the link to authorize", out)
+	}
+}
+
+func TestParseCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "code", input: " abc\n", want: "abc"},
+		{name: "address", input: "http://127.0.0.1/?state=st&code=abc&scope=drive\n", want: "abc"},
+		{name: "forged state", input: "http://127.0.0.1/?state=forged&code=abc", wantErr: true},
+		{name: "denied", input: "http://127.0.0.1/?error=access_denied&state=st", wantErr: true},
+		{name: "empty", input: "\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCode(tt.input, "st")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseCode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_Status(t *testing.T) {
+	expiry := time.Date(2021, 3, 1, 10, 0, 0, 0, time.Local)
+	tests := []struct {
+		name   string
+		status Status
+		want   []string
+	}{
+		{
+			name:   "authorized",
+			status: Status{Account: "anna@example.com", Scopes: []string{"drive", "spreadsheets"}, Expiry: expiry},
+			want:   []string{"Account: anna@example.com", "Scopes: drive spreadsheets", "expiry: " + expiry.Format(time.RFC1123)},
+		},
+		{
+			name:   "revoked",
+			status: Status{AccountErr: ErrInvalidGrant},
+			want:   []string{"Account: unknown, the authorization was revoked", "Scopes: unknown", "expiry: never"},
+		},
+		{
+			name:   "service account",
+			status: Status{Account: "bot@project.iam.gserviceaccount.com", ServiceAccount: true},
+			want:   []string{"Service account: bot@project.iam.gserviceaccount.com"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			s := NewService(terminal{strings.NewReader(""), out}, &fakeAuthRepo{status: tt.status})
+			if err := s.Status(context.Background()); err != nil {
+				t.Fatalf("Status() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("Status() printed %q, want %q", out, want)
+				}
+			}
+		})
+	}
+}
+
+func TestService_Revoke(t *testing.T) {
+	repo := &fakeAuthRepo{}
+	out := &bytes.Buffer{}
+	s := NewService(terminal{strings.NewReader(""), out}, repo)
+
+	if err := s.Revoke(context.Background()); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if !repo.revoked || !strings.Contains(out.String(), "revoked") {
+		t.Errorf("Revoke() revoked %v and printed %q", repo.revoked, out)
+	}
+}
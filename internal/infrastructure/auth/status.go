@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	googleRevokeURL = "https://oauth2.googleapis.com/revoke"
+	// driveAboutURL tells the email of the user with the drive.readonly scope
+	// every token has, as the import asks for no profile scope
+	driveAboutURL = "https://www.googleapis.com/drive/v3/about?fields=user(emailAddress)"
+)
+
+// ErrNotAuthorized is returned when there is no token file yet
+var ErrNotAuthorized = errors.New("not authorized yet, authorize with hsync auth")
+
+// Status is the authorization of a token file, or of a service account
+type Status struct {
+	Account        string
+	ServiceAccount bool
+	// AccountErr is why the account is unknown, like a revoked token
+	AccountErr error
+	// Scopes are empty for tokens saved before the scopes were stored
+	Scopes []string
+	// Expiry is the one of the access token, which is refreshed as needed
+	Expiry time.Time
+}
+
+// Status reads the token file and asks Google the email of its account
+func (ar *authRepository) Status(ctx context.Context) (Status, error) {
+	b, err := ioutil.ReadFile(ar.credentialsPath)
+	if err != nil {
+		return Status{}, err
+	}
+	if isServiceAccount(b) {
+		var key struct {
+			Email string `json:"client_email"`
+		}
+		if err := json.Unmarshal(b, &key); err != nil {
+			return Status{}, err
+		}
+		return Status{Account: key.Email, ServiceAccount: true}, nil
+	}
+
+	config, err := getConfig(ar.credentialsPath, ar.scopes...)
+	if err != nil {
+		return Status{}, err
+	}
+	client, err := tokenClient(ctx, config, ar.tokenPath, nil)
+	if os.IsNotExist(err) {
+		return Status{}, ErrNotAuthorized
+	}
+	if err != nil {
+		return Status{}, fmt.Errorf("invalid token file %v: %w", ar.tokenPath, err)
+	}
+
+	status := Status{}
+	status.Account, status.AccountErr = ar.account(ctx, client)
+	// Asking for the account may have refreshed the token
+	stored, err := tokenFromFile(ar.tokenPath)
+	if err != nil {
+		return Status{}, err
+	}
+	status.Scopes = strings.Fields(stored.Scope)
+	status.Expiry = stored.Expiry
+	return status, nil
+}
+
+func (ar *authRepository) account(ctx context.Context, client *http.Client) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ar.aboutURL, nil)
+	if err != nil {
+		return "", err
+	}
+	rsp, err := client.Do(req)
+	if err != nil {
+		var e *url.Error
+		if errors.As(err, &e) {
+			return "", e.Err
+		}
+		return "", err
+	}
+	defer func() { _ = rsp.Body.Close() }()
+	body, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		return "", err
+	}
+	if rsp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%v: %s", rsp.Status, body)
+	}
+
+	var about struct {
+		User struct {
+			EmailAddress string `json:"emailAddress"`
+		} `json:"user"`
+	}
+	if err := json.Unmarshal(body, &about); err != nil {
+		return "", err
+	}
+	return about.User.EmailAddress, nil
+}
+
+// Revoke revokes the token on Google and deletes the token file. Tokens
+// already revoked are deleted too
+func (ar *authRepository) Revoke(ctx context.Context) error {
+	stored, err := tokenFromFile(ar.tokenPath)
+	if os.IsNotExist(err) {
+		return ErrNotAuthorized
+	}
+	if err != nil {
+		return fmt.Errorf("invalid token file %v: %w", ar.tokenPath, err)
+	}
+
+	// Revoking the refresh token revokes its access tokens too
+	token := stored.RefreshToken
+	if token == "" {
+		token = stored.AccessToken
+	}
+	err = ar.revoke(ctx, token)
+	var e oauthError
+	if err != nil && !(errors.As(err, &e) && e.Code == "invalid_token") {
+		return fmt.Errorf("unable to revoke the token: %w", err)
+	}
+	return os.Remove(ar.tokenPath)
+}
+
+// oauthError is the error of an OAuth endpoint, see RFC 6749
+type oauthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (e oauthError) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Description
+}
+
+// revoke revokes the token on the revocation endpoint. Any 200 revoked it,
+// whatever its body, which RFC 7009 leaves empty
+func (ar *authRepository) revoke(ctx context.Context, token string) error {
+	form := url.Values{"token": {token}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ar.revokeURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	rsp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rsp.Body.Close() }()
+	if rsp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	body, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		return err
+	}
+	e := oauthError{}
+	if json.Unmarshal(body, &e) == nil && e.Code != "" {
+		return e
+	}
+	return fmt.Errorf("%v: %s", rsp.Status, body)
+}
@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestAuthRepository_Status(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = fmt.Fprint(w, `{"error": "invalid_grant"}`)
+	})
+	mux.HandleFunc("/about", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"user": {"emailAddress": "anna@example.com"}}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	client := credentials(t, dir, server.URL+"/token")
+	expiry := time.Now().Add(time.Hour).Round(time.Second)
+	valid := filepath.Join(dir, "auth.json")
+	tok := &oauth2.Token{AccessToken: "access", RefreshToken: "refresh", TokenType: "Bearer", Expiry: expiry}
+	if err := saveToken(valid, &storedToken{Token: tok, Scope: "drive spreadsheets"}); err != nil {
+		t.Fatal(err)
+	}
+	expired := filepath.Join(dir, "expired.json")
+	tok = &oauth2.Token{AccessToken: "old", RefreshToken: "refresh", TokenType: "Bearer", Expiry: time.Now().Add(-time.Hour)}
+	if err := saveToken(expired, &storedToken{Token: tok}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		credentials    string
+		tokenPath      string
+		want           Status
+		wantAccountErr error
+		wantErr        error
+	}{
+		{
+			name:        "authorized",
+			credentials: client,
+			tokenPath:   valid,
+			want:        Status{Account: "anna@example.com", Scopes: []string{"drive", "spreadsheets"}, Expiry: expiry},
+		},
+		{
+			name:           "revoked",
+			credentials:    client,
+			tokenPath:      expired,
+			wantAccountErr: ErrInvalidGrant,
+		},
+		{
+			name:        "not authorized",
+			credentials: client,
+			tokenPath:   filepath.Join(dir, "missing.json"),
+			wantErr:     ErrNotAuthorized,
+		},
+		{
+			name:        "service account",
+			credentials: serviceAccount(t, dir, server.URL+"/token"),
+			want:        Status{Account: "bot@project.iam.gserviceaccount.com", ServiceAccount: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := NewAuthRepository(tt.credentials, tt.tokenPath)
+			repo.aboutURL = server.URL + "/about"
+
+			got, err := repo.Status(context.Background())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Status() error = %v, want %v", err, tt.wantErr)
+			}
+			if !errors.Is(got.AccountErr, tt.wantAccountErr) {
+				t.Errorf("Status() account error = %v, want %v", got.AccountErr, tt.wantAccountErr)
+			}
+			if tt.wantAccountErr != nil {
+				return
+			}
+			if got.Account != tt.want.Account || got.ServiceAccount != tt.want.ServiceAccount ||
+				!reflect.DeepEqual(got.Scopes, tt.want.Scopes) || !got.Expiry.Equal(tt.want.Expiry) {
+				t.Errorf("Status() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthRepository_Revoke(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		answer      string
+		wantErr     bool
+		wantDeleted bool
+	}{
+		{name: "revoked", status: http.StatusOK, answer: `{}`, wantDeleted: true},
+		{name: "revoked without a body", status: http.StatusOK, answer: ``, wantDeleted: true},
+		{name: "already revoked", status: http.StatusBadRequest, answer: `{"error": "invalid_token"}`, wantDeleted: true},
+		{name: "failed", status: http.StatusServiceUnavailable, answer: `unavailable`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revoked := ""
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_ = r.ParseForm()
+				revoked = r.PostForm.Get("token")
+				w.WriteHeader(tt.status)
+				_, _ = fmt.Fprint(w, tt.answer)
+			}))
+			defer server.Close()
+
+			dir, err := ioutil.TempDir("", "auth")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = os.RemoveAll(dir) }()
+			tokenPath := filepath.Join(dir, "auth.json")
+			if err := saveToken(tokenPath, &storedToken{Token: &oauth2.Token{AccessToken: "access", RefreshToken: "refresh"}}); err != nil {
+				t.Fatal(err)
+			}
+			repo := NewAuthRepository(credentials(t, dir, server.URL), tokenPath)
+			repo.revokeURL = server.URL
+
+			err = repo.Revoke(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Revoke() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if revoked != "refresh" {
+				t.Errorf("Revoke() revoked %q, want the refresh token", revoked)
+			}
+			if _, err := os.Stat(tokenPath); os.IsNotExist(err) != tt.wantDeleted {
+				t.Errorf("Revoke() deleted the token %v, want %v", os.IsNotExist(err), tt.wantDeleted)
+			}
+		})
+	}
+}
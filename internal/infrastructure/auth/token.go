@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"golang.org/x/oauth2"
+)
+
+// ErrInvalidGrant is returned when Google rejects the refresh token of the
+// token file, because it was revoked or it expired
+var ErrInvalidGrant = errors.New("the authorization was revoked or expired, authorize again with hsync auth")
+
+// tokenClient returns an HTTP client authorized with the token of the file.
+// The tokens refreshed by the client are saved back to the file
+func tokenClient(ctx context.Context, config *oauth2.Config, tokenPath string, features []Feature) (*http.Client, error) {
+	stored, err := tokenFromFile(tokenPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkScopes(stored.Scope, features); err != nil {
+		return nil, err
+	}
+	src := &savingTokenSource{
+		base: config.TokenSource(ctx, stored.Token),
+		path: tokenPath,
+		last: stored,
+	}
+	return oauth2.NewClient(ctx, oauth2.ReuseTokenSource(stored.Token, src)), nil
+}
+
+// storedToken is the content of the token file: the token, and the scopes
+// granted to it separated by spaces
+type storedToken struct {
+	*oauth2.Token
+	Scope string `json:"scope,omitempty"`
+}
+
+// newStoredToken keeps the scopes granted by the response of the token
+// endpoint, or the given ones when it has none
+func newStoredToken(tok *oauth2.Token, scope string) *storedToken {
+	if granted, ok := tok.Extra("scope").(string); ok && granted != "" {
+		scope = granted
+	}
+	return &storedToken{Token: tok, Scope: scope}
+}
+
+// savingTokenSource saves the tokens of the base source when they change. It
+// is not safe for concurrent use, see oauth2.ReuseTokenSource
+type savingTokenSource struct {
+	base oauth2.TokenSource
+	path string
+	last *storedToken
+}
+
+func (s *savingTokenSource) Token() (*oauth2.Token, error) {
+	tok, err := s.base.Token()
+	if err != nil {
+		var e *oauth2.RetrieveError
+		if errors.As(err, &e) && invalidGrant(e.Body) {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidGrant, err)
+		}
+		return nil, err
+	}
+
+	if s.last == nil || tok.AccessToken != s.last.AccessToken || tok.RefreshToken != s.last.RefreshToken {
+		scope := ""
+		if s.last != nil {
+			scope = s.last.Scope
+		}
+		s.last = newStoredToken(tok, scope)
+		// The token is still good for this run, so the import goes on
+		if err := saveToken(s.path, s.last); err != nil {
+			log.Printf("unable to save the refreshed token: %v", err)
+		}
+	}
+	return tok, nil
+}
+
+// invalidGrant tells whether the body of a token error is an invalid_grant one
+func invalidGrant(body []byte) bool {
+	var rsp struct {
+		Error string `json:"error"`
+	}
+	return json.Unmarshal(body, &rsp) == nil && rsp.Error == "invalid_grant"
+}
+
+// ErrInsecureToken is returned when other users can read the token file
+var ErrInsecureToken = errors.New("the token file can be read by other users")
+
+// Retrieves a token from a local file, decrypting it with the passphrase of
+// the PassphraseEnv when it's encrypted
+func tokenFromFile(file string) (*storedToken, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	if err := checkPermissions(f); err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &encryptedToken{}
+	if err := json.Unmarshal(b, e); err != nil {
+		return nil, err
+	}
+	if e.Encryption != "" {
+		passphrase := os.Getenv(PassphraseEnv)
+		if passphrase == "" {
+			return nil, fmt.Errorf("the token file is encrypted, set its passphrase on %v", PassphraseEnv)
+		}
+		if b, err = e.decrypt(passphrase); err != nil {
+			return nil, fmt.Errorf("unable to decrypt the token file: %w", err)
+		}
+	}
+	tok := &storedToken{Token: &oauth2.Token{}}
+	err = json.Unmarshal(b, tok)
+	return tok, err
+}
+
+// checkPermissions refuses token files other users can access, as ssh does
+// with private keys. Windows has no such permissions
+func checkPermissions(f *os.File) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("%w, restrict it with chmod 600 %v", ErrInsecureToken, f.Name())
+	}
+	return nil
+}
+
+// saveToken writes the token to a temporary file renamed after it, so the
+// token file is never left half written. It's encrypted when the
+// PassphraseEnv is set
+func saveToken(path string, token *storedToken) error {
+	var content interface{} = token
+	if passphrase := os.Getenv(PassphraseEnv); passphrase != "" {
+		plain, err := json.Marshal(token)
+		if err != nil {
+			return fmt.Errorf("unable to cache oauth token: %v", err)
+		}
+		if content, err = encrypt(plain, passphrase); err != nil {
+			return fmt.Errorf("unable to encrypt oauth token: %v", err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("unable to cache oauth token: %v", err)
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("unable to cache oauth token: %v", err)
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+
+	if err := json.NewEncoder(f).Encode(content); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("unable to cache oauth token: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("unable to cache oauth token: %v", err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("unable to cache oauth token: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+type fakeTokenSource struct {
+	tokens []*oauth2.Token
+	err    error
+}
+
+func (f *fakeTokenSource) Token() (*oauth2.Token, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	tok := f.tokens[0]
+	f.tokens = f.tokens[1:]
+	return tok, nil
+}
+
+func TestSavingTokenSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	path := filepath.Join(dir, "auth.json")
+
+	first := &oauth2.Token{AccessToken: "first", RefreshToken: "refresh"}
+	if err := saveToken(path, &storedToken{Token: first, Scope: "drive"}); err != nil {
+		t.Fatal(err)
+	}
+	refreshed := &oauth2.Token{AccessToken: "second", RefreshToken: "refresh"}
+	src := &savingTokenSource{
+		base: &fakeTokenSource{tokens: []*oauth2.Token{first, refreshed, refreshed}},
+		path: path,
+		last: &storedToken{Token: first, Scope: "drive"},
+	}
+
+	for i, want := range []string{"first", "second", "second"} {
+		tok, err := src.Token()
+		if err != nil {
+			t.Fatalf("Token() error = %v", err)
+		}
+		saved, err := tokenFromFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.AccessToken != want || saved.AccessToken != want {
+			t.Errorf("Token() #%v = %v, saved %v, want %v", i, tok.AccessToken, saved.AccessToken, want)
+		}
+		if saved.Scope != "drive" || saved.RefreshToken != "refresh" {
+			t.Errorf("Token() #%v saved the scope %q and refresh token %q, want them kept", i, saved.Scope, saved.RefreshToken)
+		}
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Mode().Perm() != 0600 {
+		t.Errorf("saveToken() left %v files, the token with mode %v, want only the token with 0600", len(files), files[0].Mode().Perm())
+	}
+}
+
+func TestSavingTokenSource_invalidGrant(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		revoked bool
+	}{
+		{
+			name:    "revoked",
+			err:     &oauth2.RetrieveError{Body: []byte(`{"error": "invalid_grant", "error_description": "Token has been expired or revoked."}`)},
+			revoked: true,
+		},
+		{
+			name: "other token errors",
+			err:  &oauth2.RetrieveError{Body: []byte(`{"error": "invalid_client"}`)},
+		},
+		{
+			name: "network errors",
+			err:  errors.New("connection refused"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := &savingTokenSource{base: &fakeTokenSource{err: tt.err}}
+			_, err := src.Token()
+			if got := errors.Is(err, ErrInvalidGrant); got != tt.revoked {
+				t.Errorf("Token() error = %v, want ErrInvalidGrant %v", err, tt.revoked)
+			}
+		})
+	}
+}
+
+func TestTokenFromFile_permissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows has no permissions for other users")
+	}
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	path := filepath.Join(dir, "auth.json")
+	if err := saveToken(path, &storedToken{Token: &oauth2.Token{AccessToken: "access"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		mode    os.FileMode
+		wantErr bool
+	}{
+		{name: "owner only", mode: 0600},
+		{name: "read only", mode: 0400},
+		{name: "group", mode: 0640, wantErr: true},
+		{name: "world", mode: 0604, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.Chmod(path, tt.mode); err != nil {
+				t.Fatal(err)
+			}
+			_, err := tokenFromFile(path)
+			if errors.Is(err, ErrInsecureToken) != tt.wantErr {
+				t.Errorf("tokenFromFile() error = %v, want ErrInsecureToken %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSaveToken_encrypted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	defer func() { _ = os.Unsetenv(PassphraseEnv) }()
+	path := filepath.Join(dir, "tokens", "auth.json")
+
+	_ = os.Setenv(PassphraseEnv, "secret")
+	if err := saveToken(path, &storedToken{Token: &oauth2.Token{AccessToken: "access", RefreshToken: "refresh"}, Scope: "drive"}); err != nil {
+		t.Fatalf("saveToken() error = %v", err)
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(content), "refresh") {
+		t.Errorf("saveToken() saved %s, want it encrypted", content)
+	}
+
+	tests := []struct {
+		name       string
+		passphrase string
+		wantErr    bool
+	}{
+		{name: "passphrase", passphrase: "secret"},
+		{name: "wrong passphrase", passphrase: "guess", wantErr: true},
+		{name: "no passphrase", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_ = os.Setenv(PassphraseEnv, tt.passphrase)
+			tok, err := tokenFromFile(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("tokenFromFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (tok.RefreshToken != "refresh" || tok.Scope != "drive") {
+				t.Errorf("tokenFromFile() = %+v, want the saved token", tok)
+			}
+		})
+	}
+}
@@ -1,38 +1,42 @@
 package drive
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"habitsSync/internal/domain"
+	"habitsSync/internal/infrastructure/auth"
+	"habitsSync/internal/infrastructure/retry"
 	"io/ioutil"
-	"os"
+	"net/http"
 	"strings"
+	"time"
 
-	"golang.org/x/oauth2/google"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
 
-	"golang.org/x/net/context"
-	"golang.org/x/oauth2"
 	"google.golang.org/api/drive/v3"
 )
 
+const (
+	folderMimeType      = "application/vnd.google-apps.folder"
+	spreadsheetMimeType = "application/vnd.google-apps.spreadsheet"
+)
+
 type repository struct {
 	client *drive.Service
 }
 
-func NewRepository(credentialsPaths, tokenPath string) (*repository, error) {
-	config, err := getConfig(credentialsPaths)
-	if err != nil {
-		return nil, fmt.Errorf("unable to parse client secret file to config: %v", err)
-	}
-	tok, err := tokenFromFile(tokenPath)
+// NewRepository authorizes the client with the context of the command, which
+// also bounds the refreshes of its token
+func NewRepository(ctx context.Context, provider *auth.Provider) (*repository, error) {
+	authorized, err := provider.Client(ctx)
 	if err != nil {
 		return nil, err
 	}
-	client := config.Client(context.Background(), tok)
+	client := retry.Client(authorized, retry.DefaultPolicy)
 
-	srv, err := drive.NewService(context.Background(), option.WithHTTPClient(client))
+	srv, err := drive.NewService(ctx, option.WithHTTPClient(client))
 	if err != nil {
 		return nil, fmt.Errorf("unable to retrieve Drive client: %v", err)
 	}
@@ -42,15 +46,7 @@ func NewRepository(credentialsPaths, tokenPath string) (*repository, error) {
 	}, nil
 }
 
-func getConfig(credentialsPath string) (*oauth2.Config, error) {
-	b, err := ioutil.ReadFile(credentialsPath)
-	if err != nil {
-		return nil, err
-	}
-	return google.ConfigFromJSON(b, drive.DriveMetadataReadonlyScope, drive.DriveReadonlyScope)
-}
-
-func (r *repository) ListByPrefix(contains string) ([]domain.File, error) {
+func (r *repository) ListByPrefix(ctx context.Context, contains string) ([]domain.File, error) {
 	if strings.Contains(contains, "'") {
 		return nil, errors.New("prefix contains unsupported single quote character")
 	}
@@ -58,7 +54,8 @@ func (r *repository) ListByPrefix(contains string) ([]domain.File, error) {
 	rsp, err := r.client.Files.List().
 		Q(fmt.Sprintf("name contains '%v'", contains)).
 		PageSize(30).
-		Fields("nextPageToken, files(id, name)").
+		Fields("nextPageToken, files(id, name, modifiedTime)").
+		Context(ctx).
 		Do()
 
 	if err != nil {
@@ -67,32 +64,84 @@ func (r *repository) ListByPrefix(contains string) ([]domain.File, error) {
 
 	lr := make([]domain.File, 0)
 	for _, r := range rsp.Files {
+		modified, _ := time.Parse(time.RFC3339, r.ModifiedTime)
 		lr = append(lr, domain.File{
-			ID:   r.Id,
-			Name: r.Name,
+			ID:       r.Id,
+			Name:     r.Name,
+			Modified: modified,
 		})
 	}
 
 	return lr, nil
 }
 
-func (r *repository) Download(id string) ([]byte, error) {
-	rsp, err := r.client.Files.Get(id).Download()
+func (r *repository) ListFolders(ctx context.Context, name string) ([]domain.File, error) {
+	if strings.Contains(name, "'") {
+		return nil, errors.New("folder name contains unsupported single quote character")
+	}
+
+	rsp, err := r.client.Files.List().
+		Q(fmt.Sprintf("mimeType = '%v' and name = '%v' and trashed = false", folderMimeType, name)).
+		PageSize(30).
+		Fields("files(id, name)").
+		Context(ctx).
+		Do()
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("unable to retrieve folders: %v", err)
 	}
-	defer func() { _ = rsp.Body.Close() }()
-	return ioutil.ReadAll(rsp.Body)
+
+	folders := make([]domain.File, 0, len(rsp.Files))
+	for _, f := range rsp.Files {
+		folders = append(folders, domain.File{ID: f.Id, Name: f.Name})
+	}
+	return folders, nil
 }
 
-// Retrieves a token from a local file.
-func tokenFromFile(file string) (*oauth2.Token, error) {
-	f, err := os.Open(file)
+func (r *repository) ListSpreadsheets(ctx context.Context, name string) ([]domain.File, error) {
+	if strings.Contains(name, "'") {
+		return nil, errors.New("spreadsheet name contains unsupported single quote character")
+	}
+
+	rsp, err := r.client.Files.List().
+		Q(fmt.Sprintf("mimeType = '%v' and name = '%v' and trashed = false", spreadsheetMimeType, name)).
+		PageSize(30).
+		Fields("files(id, name)").
+		Context(ctx).
+		Do()
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve spreadsheets: %v", err)
+	}
+
+	spreadsheets := make([]domain.File, 0, len(rsp.Files))
+	for _, f := range rsp.Files {
+		spreadsheets = append(spreadsheets, domain.File{ID: f.Id, Name: f.Name})
+	}
+	return spreadsheets, nil
+}
+
+// CreateSpreadsheet needs the drive.file scope, see auth.CreateSpreadsheets
+func (r *repository) CreateSpreadsheet(ctx context.Context, name string, folder string) (domain.File, error) {
+	file := &drive.File{Name: name, MimeType: spreadsheetMimeType}
+	if folder != "" {
+		file.Parents = []string{folder}
+	}
+
+	created, err := r.client.Files.Create(file).Fields("id, name").Context(ctx).Do()
+	if err != nil {
+		var e *googleapi.Error
+		if errors.As(err, &e) && e.Code == http.StatusForbidden {
+			return domain.File{}, fmt.Errorf("%v. Authorize again with hsync auth -create", err)
+		}
+		return domain.File{}, err
+	}
+	return domain.File{ID: created.Id, Name: created.Name}, nil
+}
+
+func (r *repository) Download(ctx context.Context, id string) ([]byte, error) {
+	rsp, err := r.client.Files.Get(id).Context(ctx).Download()
 	if err != nil {
 		return nil, err
 	}
-	defer func() { _ = f.Close() }()
-	tok := &oauth2.Token{}
-	err = json.NewDecoder(f).Decode(tok)
-	return tok, err
+	defer func() { _ = rsp.Body.Close() }()
+	return ioutil.ReadAll(rsp.Body)
 }
@@ -1,7 +1,9 @@
 package drive
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"habitsSync/internal/domain"
 	"path"
 	"time"
@@ -9,13 +11,37 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
-const allHabitsQuery = `select Habits.Id, Habits.name, count(Repetitions.id)
+const allHabitsQuery = `select Habits.Id, Habits.name, coalesce(Habits.question, ''), coalesce(Habits.description, ''),
+	Habits.color, Habits.position, count(Repetitions.id)
 	from Habits
 	left join Repetitions on Habits.Id = Repetitions.habit
 	where not Habits.archived and Repetitions.value = 2
 	and Repetitions.timestamp >= ? and Repetitions.timestamp <= ?
 	group by Habits.Id
-	order by Habits.name, Habits.Id`
+	order by Habits.position, Habits.Id`
+
+const repetitionsQuery = `select Habits.Id, Habits.name, Repetitions.timestamp
+	from Habits
+	join Repetitions on Habits.Id = Repetitions.habit
+	where not Habits.archived and Repetitions.value = 2
+	and Repetitions.timestamp >= ? and Repetitions.timestamp <= ?
+	order by Habits.position, Habits.Id, Repetitions.timestamp`
+
+// Notes were added on Loop 2.1, older backups don't have the column
+const journalQuery = `select Repetitions.timestamp, Habits.Id, Habits.name, Habits.type, Repetitions.value, Repetitions.notes
+	from Repetitions
+	join Habits on Habits.Id = Repetitions.habit
+	where not Habits.archived and Repetitions.notes is not null and Repetitions.notes != ''
+	and Repetitions.timestamp >= ? and Repetitions.timestamp <= ?
+	order by Repetitions.timestamp, Habits.position, Habits.Id`
+
+const (
+	numericalHabit = 1
+	// Yes/no habits store 2 when done manually and 1 when done implicitly
+	// because of the frequency of the habit
+	yesManual = 2
+	yesAuto   = 1
+)
 
 type storageFactory struct {
 	path string
@@ -45,8 +71,8 @@ func NewStorage(path string) (*Storage, error) {
 	}, nil
 }
 
-func (d *Storage) AllHabits(from, to time.Time) ([]domain.Habit, error) {
-	result, err := d.db.Query(allHabitsQuery, from.Unix()*1000, to.Unix()*1000)
+func (d *Storage) AllHabits(ctx context.Context, from, to time.Time) ([]domain.Habit, error) {
+	result, err := d.db.QueryContext(ctx, allHabitsQuery, from.Unix()*1000, to.Unix()*1000)
 	if err != nil {
 		return nil, err
 	}
@@ -55,10 +81,60 @@ func (d *Storage) AllHabits(from, to time.Time) ([]domain.Habit, error) {
 	stats := make([]domain.Habit, 0)
 	for result.Next() {
 		s := domain.Habit{}
-		if err = result.Scan(&s.ID, &s.Name, &s.Count); err != nil {
+		err = result.Scan(&s.ID, &s.Name, &s.Question, &s.Description, &s.Color, &s.Position, &s.Count)
+		if err != nil {
 			return nil, err
 		}
 		stats = append(stats, s)
 	}
 	return stats, nil
 }
+
+func (d *Storage) Repetitions(ctx context.Context, from, to time.Time) ([]domain.Repetition, error) {
+	result, err := d.db.QueryContext(ctx, repetitionsQuery, from.Unix()*1000, to.Unix()*1000)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = result.Close() }()
+
+	repetitions := make([]domain.Repetition, 0)
+	for result.Next() {
+		var timestamp int64
+		r := domain.Repetition{}
+		if err = result.Scan(&r.HabitID, &r.HabitName, &timestamp); err != nil {
+			return nil, err
+		}
+		// Loop stores the day of the repetition as milliseconds at UTC midnight
+		r.Timestamp = time.Unix(timestamp/1000, 0).UTC()
+		repetitions = append(repetitions, r)
+	}
+	return repetitions, result.Err()
+}
+
+func (d *Storage) Journal(ctx context.Context, from, to time.Time) ([]domain.JournalEntry, error) {
+	result, err := d.db.QueryContext(ctx, journalQuery, from.Unix()*1000, to.Unix()*1000)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the notes, the backup may be from a Loop version without notes: %w", err)
+	}
+	defer func() { _ = result.Close() }()
+
+	entries := make([]domain.JournalEntry, 0)
+	for result.Next() {
+		var timestamp int64
+		var habitType, value int
+		e := domain.JournalEntry{}
+		if err = result.Scan(&timestamp, &e.HabitID, &e.HabitName, &habitType, &value, &e.Note); err != nil {
+			return nil, err
+		}
+		e.Date = time.Unix(timestamp/1000, 0).UTC()
+		switch {
+		case habitType == numericalHabit:
+			// Numerical habits store the amount multiplied by 1000
+			e.Value = float64(value) / 1000
+		case value == yesManual || value == yesAuto:
+			e.Value = 1
+		}
+		entries = append(entries, e)
+	}
+	return entries, result.Err()
+}
@@ -0,0 +1,155 @@
+package drive
+
+import (
+	"context"
+	"database/sql"
+	"habitsSync/internal/domain"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// day is the timestamp Loop stores for a day of January 2021
+func day(d int) int64 {
+	return time.Date(2021, 1, d, 0, 0, 0, 0, time.UTC).Unix() * 1000
+}
+
+// backupStorage writes a backup with the tables of Loop, with the notes
+// column when notes, and opens it
+func backupStorage(t *testing.T, notes bool) *Storage {
+	dir, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	path := filepath.Join(dir, "backup.db")
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+	repetitions := "create table Repetitions (id integer primary key, habit integer, timestamp integer, value integer)"
+	if notes {
+		repetitions = "create table Repetitions (id integer primary key, habit integer, timestamp integer, value integer, notes text)"
+	}
+	statements := []string{
+		`create table Habits (id integer primary key, name text, question text, description text, color integer,
+			position integer, archived integer, type integer)`,
+		repetitions,
+		`insert into Habits values
+			(1, 'Run', 'Did you run today?', '5k at least', 3, 1, 0, 0),
+			(2, 'Read', null, null, 7, 0, 0, 0),
+			(3, 'Pages', 'How many pages?', '', 1, 2, 0, 1),
+			(4, 'Old', '', '', 0, 3, 1, 0)`,
+	}
+	for _, s := range statements {
+		if _, err := db.Exec(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type repetition struct {
+		habit     int
+		timestamp int64
+		value     int
+		note      interface{}
+	}
+	rows := []repetition{
+		{habit: 1, timestamp: day(4), value: yesManual, note: "Easy"},
+		{habit: 1, timestamp: day(5), value: yesAuto, note: "Rest day"},
+		{habit: 1, timestamp: day(6), value: yesManual, note: ""},
+		{habit: 1, timestamp: day(4) + 31*24*3600*1000, value: yesManual, note: "February"},
+		{habit: 2, timestamp: day(4), value: yesManual},
+		{habit: 2, timestamp: day(5), value: yesManual},
+		{habit: 3, timestamp: day(5), value: 12500, note: "Long chapter"},
+		{habit: 4, timestamp: day(4), value: yesManual, note: "Archived"},
+	}
+	for _, r := range rows {
+		if notes {
+			_, err = db.Exec("insert into Repetitions (habit, timestamp, value, notes) values (?, ?, ?, ?)", r.habit, r.timestamp, r.value, r.note)
+		} else {
+			_, err = db.Exec("insert into Repetitions (habit, timestamp, value) values (?, ?, ?)", r.habit, r.timestamp, r.value)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := NewStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+var (
+	january      = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	endOfJanuary = time.Date(2021, 1, 31, 23, 59, 59, 0, time.UTC)
+)
+
+func TestStorage_Journal(t *testing.T) {
+	s := backupStorage(t, true)
+
+	got, err := s.Journal(context.Background(), january, endOfJanuary)
+	if err != nil {
+		t.Fatalf("Journal() error = %v", err)
+	}
+	// Ordered by day and then by the position of the habit on Loop
+	want := []domain.JournalEntry{
+		{Date: time.Unix(day(4)/1000, 0).UTC(), HabitID: 1, HabitName: "Run", Value: 1, Note: "Easy"},
+		{Date: time.Unix(day(5)/1000, 0).UTC(), HabitID: 1, HabitName: "Run", Value: 1, Note: "Rest day"},
+		{Date: time.Unix(day(5)/1000, 0).UTC(), HabitID: 3, HabitName: "Pages", Value: 12.5, Note: "Long chapter"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Journal() = %+v, want %+v", got, want)
+	}
+}
+
+func TestStorage_Journal_withoutNotes(t *testing.T) {
+	s := backupStorage(t, false)
+
+	if _, err := s.Journal(context.Background(), january, endOfJanuary); err == nil {
+		t.Error("Journal() error = nil, want the backup without notes to fail")
+	}
+}
+
+func TestStorage_AllHabits(t *testing.T) {
+	s := backupStorage(t, true)
+
+	got, err := s.AllHabits(context.Background(), january, endOfJanuary)
+	if err != nil {
+		t.Fatalf("AllHabits() error = %v", err)
+	}
+	// Ordered by their position on Loop. Repetitions done implicitly because
+	// of the frequency of the habit are not counted
+	want := []domain.Habit{
+		{ID: 2, Name: "Read", Color: 7, Position: 0, Count: 2},
+		{ID: 1, Name: "Run", Question: "Did you run today?", Description: "5k at least", Color: 3, Position: 1, Count: 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("AllHabits() = %+v, want %+v", got, want)
+	}
+}
+
+func TestStorage_Repetitions(t *testing.T) {
+	s := backupStorage(t, true)
+
+	got, err := s.Repetitions(context.Background(), january, endOfJanuary)
+	if err != nil {
+		t.Fatalf("Repetitions() error = %v", err)
+	}
+	at := func(d int) time.Time { return time.Unix(day(d)/1000, 0).UTC() }
+	want := []domain.Repetition{
+		{HabitID: 2, HabitName: "Read", Timestamp: at(4)},
+		{HabitID: 2, HabitName: "Read", Timestamp: at(5)},
+		{HabitID: 1, HabitName: "Run", Timestamp: at(4)},
+		{HabitID: 1, HabitName: "Run", Timestamp: at(6)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Repetitions() = %+v, want %+v", got, want)
+	}
+}
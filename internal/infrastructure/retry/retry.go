@@ -0,0 +1,208 @@
+// Package retry retries the calls to the Google APIs that fail because of the
+// quotas or of transient errors of the servers
+package retry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Policy tells how many times and how long to wait before retrying a call
+type Policy struct {
+	// MaxAttempts counts the first attempt too
+	MaxAttempts int
+	// BaseDelay is the longest wait before the first retry, doubled on each
+	// one of the next ones up to MaxDelay. The wait is a random amount up to it
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+var DefaultPolicy = Policy{
+	MaxAttempts: 5,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    32 * time.Second,
+}
+
+// Transport retries the requests following the Policy:
+//   - 429 Too Many Requests is retried for any request, since the API rejects
+//     them before doing anything. So is 403 Forbidden when its reason is a rate
+//     limit, as Drive answers to the quota errors
+//   - 5xx errors and network errors are only retried on idempotent requests,
+//     since the call could have been done anyway. Other errors are not retried
+//
+// A Retry-After header is honored, unless it asks to wait more than MaxDelay
+type Transport struct {
+	base   http.RoundTripper
+	policy Policy
+	sleep  func(ctx context.Context, d time.Duration) error
+}
+
+func NewTransport(base http.RoundTripper, policy Policy) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{base: base, policy: policy, sleep: sleep}
+}
+
+// Client returns a copy of the client retrying its requests
+func Client(c *http.Client, policy Policy) *http.Client {
+	retrying := *c
+	retrying.Transport = NewTransport(c.Transport, policy)
+	return &retrying
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		rsp, err := t.base.RoundTrip(req)
+		if attempt >= t.policy.MaxAttempts || !t.retryable(req, rsp, err) {
+			return rsp, err
+		}
+
+		wait := t.backoff(attempt)
+		if rsp != nil {
+			if after, ok := retryAfter(rsp.Header.Get("Retry-After"), time.Now()); ok {
+				if after > t.policy.MaxDelay {
+					return rsp, err
+				}
+				wait = after
+			}
+		}
+
+		if req.Body != nil && req.Body != http.NoBody {
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return rsp, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+		if rsp != nil {
+			_, _ = io.Copy(ioutil.Discard, rsp.Body)
+			_ = rsp.Body.Close()
+		}
+
+		if err := t.sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (t *Transport) retryable(req *http.Request, rsp *http.Response, err error) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false // The body cannot be sent again
+	}
+	if err != nil {
+		return req.Context().Err() == nil && network(err) && idempotent(req)
+	}
+	switch {
+	case rsp.StatusCode == http.StatusTooManyRequests:
+		return true
+	case rsp.StatusCode == http.StatusForbidden:
+		return rateLimited(rsp)
+	case rsp.StatusCode >= 500 && rsp.StatusCode != http.StatusNotImplemented:
+		return idempotent(req)
+	}
+	return false
+}
+
+// rateLimitReasons are the reasons of the 403 errors caused by the quotas
+var rateLimitReasons = map[string]bool{
+	"userRateLimitExceeded": true,
+	"rateLimitExceeded":     true,
+}
+
+// rateLimited tells whether the error of the response is a quota one. The body
+// is read, and put back for the caller
+func rateLimited(rsp *http.Response) bool {
+	body, err := ioutil.ReadAll(rsp.Body)
+	_ = rsp.Body.Close()
+	rsp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+
+	var e struct {
+		Error struct {
+			Errors []struct {
+				Reason string `json:"reason"`
+			} `json:"errors"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &e); err != nil {
+		return false
+	}
+	for _, r := range e.Error.Errors {
+		if rateLimitReasons[r.Reason] {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff is the wait before the retry after the attempt: a random duration
+// up to an exponential limit
+func (t *Transport) backoff(attempt int) time.Duration {
+	limit := t.policy.BaseDelay
+	for i := 1; i < attempt && limit < t.policy.MaxDelay; i++ {
+		limit *= 2
+	}
+	if limit > t.policy.MaxDelay {
+		limit = t.policy.MaxDelay
+	}
+	if limit <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(limit)))
+}
+
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// network tells whether the error is a network one. Any other one, like the
+// failure to refresh the token, would fail again
+func network(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// retryAfter parses the header, either in seconds or as a date
+func retryAfter(header string, now time.Time) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		if d := date.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,213 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// failingServer answers with the statuses, one per request, and 200 OK after
+// them. It records the bodies of the requests
+type failingServer struct {
+	mu       sync.Mutex
+	statuses []int
+	header   http.Header
+	// body is the one of the errors
+	body   string
+	bodies []string
+}
+
+func (f *failingServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	body, _ := ioutil.ReadAll(r.Body)
+	f.bodies = append(f.bodies, string(body))
+	if len(f.statuses) == 0 {
+		_, _ = w.Write([]byte("ok"))
+		return
+	}
+	for k, v := range f.header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(f.statuses[0])
+	_, _ = w.Write([]byte(f.body))
+	f.statuses = f.statuses[1:]
+}
+
+// googleError is the body of an error of the Google APIs with the reason
+func googleError(reason string) string {
+	return `{"error": {"code": 403, "errors": [{"domain": "usageLimits", "reason": "` + reason + `"}]}}`
+}
+
+func TestTransport(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		statuses     []int
+		body         string
+		wantStatus   int
+		wantAttempts int
+	}{
+		{name: "no errors", method: http.MethodGet, wantStatus: 200, wantAttempts: 1},
+		{name: "unavailable", method: http.MethodGet, statuses: []int{503, 503}, wantStatus: 200, wantAttempts: 3},
+		{name: "quota on any method", method: http.MethodPost, statuses: []int{429}, wantStatus: 200, wantAttempts: 2},
+		{name: "no retries on not idempotent", method: http.MethodPost, statuses: []int{503}, wantStatus: 503, wantAttempts: 1},
+		{name: "no retries on client errors", method: http.MethodGet, statuses: []int{400}, wantStatus: 400, wantAttempts: 1},
+		{name: "max attempts", method: http.MethodGet, statuses: []int{500, 502, 503, 504}, wantStatus: 503, wantAttempts: 3},
+		{name: "user rate limit", method: http.MethodPost, statuses: []int{403}, body: googleError("userRateLimitExceeded"), wantStatus: 200, wantAttempts: 2},
+		{name: "rate limit", method: http.MethodPost, statuses: []int{403}, body: googleError("rateLimitExceeded"), wantStatus: 200, wantAttempts: 2},
+		{name: "no retries on forbidden", method: http.MethodGet, statuses: []int{403}, body: googleError("insufficientPermissions"), wantStatus: 403, wantAttempts: 1},
+		{name: "no retries on forbidden without reason", method: http.MethodGet, statuses: []int{403}, body: "forbidden", wantStatus: 403, wantAttempts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &failingServer{statuses: tt.statuses, body: tt.body}
+			s := httptest.NewServer(server)
+			defer s.Close()
+
+			transport := NewTransport(nil, Policy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond})
+			req, err := http.NewRequest(tt.method, s.URL, strings.NewReader("payload"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rsp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("RoundTrip() error = %v", err)
+			}
+			body, _ := ioutil.ReadAll(rsp.Body)
+			_ = rsp.Body.Close()
+			if rsp.StatusCode != 200 && string(body) != tt.body {
+				t.Errorf("RoundTrip() body = %q, want %q", body, tt.body)
+			}
+
+			if rsp.StatusCode != tt.wantStatus {
+				t.Errorf("RoundTrip() status = %v, want %v", rsp.StatusCode, tt.wantStatus)
+			}
+			if len(server.bodies) != tt.wantAttempts {
+				t.Errorf("RoundTrip() attempts = %v, want %v", len(server.bodies), tt.wantAttempts)
+			}
+			for _, b := range server.bodies {
+				if b != "payload" {
+					t.Errorf("RoundTrip() sent the body %q, want it on every attempt", b)
+				}
+			}
+		})
+	}
+}
+
+func TestTransport_retryAfter(t *testing.T) {
+	server := &failingServer{statuses: []int{429}, header: http.Header{"Retry-After": []string{"2"}}}
+	s := httptest.NewServer(server)
+	defer s.Close()
+
+	transport := NewTransport(nil, Policy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Second})
+	var waits []time.Duration
+	transport.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, s.URL, nil)
+	rsp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip() error = %v", err)
+	}
+	_ = rsp.Body.Close()
+	if rsp.StatusCode != 200 || len(waits) != 1 || waits[0] != 2*time.Second {
+		t.Errorf("RoundTrip() = %v after waiting %v, want 200 after 2s", rsp.StatusCode, waits)
+	}
+
+	transport.policy.MaxDelay = time.Second
+	server.statuses = []int{429}
+	rsp, err = transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip() error = %v", err)
+	}
+	_ = rsp.Body.Close()
+	if rsp.StatusCode != 429 {
+		t.Errorf("RoundTrip() = %v, want to give up when asked to wait more than MaxDelay", rsp.StatusCode)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestTransport_errors(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		wantAttempts int
+	}{
+		{name: "network", err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, wantAttempts: 3},
+		{name: "connection closed", err: io.ErrUnexpectedEOF, wantAttempts: 3},
+		{name: "token", err: errors.New("oauth2: cannot fetch token"), wantAttempts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			base := roundTripperFunc(func(*http.Request) (*http.Response, error) {
+				attempts++
+				return nil, tt.err
+			})
+			transport := NewTransport(base, Policy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})
+			req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+
+			if _, err := transport.RoundTrip(req); err != tt.err {
+				t.Errorf("RoundTrip() error = %v, want %v", err, tt.err)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("RoundTrip() attempts = %v, want %v", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestTransport_canceled(t *testing.T) {
+	server := &failingServer{statuses: []int{503, 503, 503}}
+	s := httptest.NewServer(server)
+	defer s.Close()
+
+	transport := NewTransport(nil, Policy{MaxAttempts: 5, BaseDelay: time.Hour, MaxDelay: time.Hour})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+
+	if _, err := transport.RoundTrip(req); err != context.DeadlineExceeded {
+		t.Errorf("RoundTrip() error = %v, want the deadline of the request", err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header string
+		want   time.Duration
+		wantOK bool
+	}{
+		{header: "", wantOK: false},
+		{header: "120", want: 2 * time.Minute, wantOK: true},
+		{header: "Mon, 01 Mar 2021 10:00:30 GMT", want: 30 * time.Second, wantOK: true},
+		{header: "Mon, 01 Mar 2021 09:00:00 GMT", want: 0, wantOK: true},
+		{header: "soon", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			got, ok := retryAfter(tt.header, now)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("retryAfter() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package sheets
+
+import "habitsSync/internal/domain"
+
+// appendRows returns the rows of the table to be appended after the existing
+// ones: the header if the Sheet is empty, and the rows whose key is not on the
+// Sheet yet. The existing rows start at the header
+func appendRows(existing [][]interface{}, table domain.Table) [][]interface{} {
+	key := table.KeyColumns()
+	seen := make(map[string]bool, len(existing))
+	for _, row := range existing {
+		seen[rowKey(row, key)] = true
+	}
+
+	rows := make([][]interface{}, 0, len(table.Rows)+1)
+	if len(existing) == 0 || isEmptyRow(existing[0]) {
+		rows = append(rows, table.Header())
+	}
+	for _, row := range table.Rows {
+		k := rowKey(row, key)
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		rows = append(rows, userEnteredValues(row))
+	}
+
+	return rows
+}
@@ -0,0 +1,41 @@
+package sheets
+
+import (
+	"habitsSync/internal/domain"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAppendRows(t *testing.T) {
+	syncedAt := time.Date(2021, 3, 10, 18, 30, 0, 0, time.UTC)
+	from := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2021, 3, 31, 23, 59, 59, 0, time.UTC)
+	table := domain.HistoryTable(domain.HabitsTable([]domain.Habit{
+		{ID: 1, Name: "run", Color: -1, Count: 10},
+		{ID: 2, Name: "read", Color: -1, Count: 3},
+	}), syncedAt, from, to)
+
+	t.Run("write the header on an empty sheet", func(t *testing.T) {
+		rows := appendRows(nil, table)
+		if len(rows) != 3 || !reflect.DeepEqual(rows[0], table.Header()) {
+			t.Errorf("appendRows() = %v, want the header and 2 rows", rows)
+		}
+		want := []interface{}{"2021-03-10", "2021-03-10 18:30:00", "2021-01-01", "2021-03-31", 1, "run", 10, "", ""}
+		if !reflect.DeepEqual(rows[1], want) {
+			t.Errorf("appendRows() row = %v, want %v", rows[1], want)
+		}
+	})
+
+	t.Run("skip the rows synced the same day for the same range", func(t *testing.T) {
+		existing := [][]interface{}{
+			table.Header(),
+			{44265.0, 44265.5, 44197.0, 44286.0, 1.0, "run", 8.0},  // same day and range
+			{44264.0, 44264.5, 44197.0, 44286.0, 2.0, "read", 1.0}, // the day before
+		}
+		rows := appendRows(existing, table)
+		if len(rows) != 1 || rows[0][5] != "read" {
+			t.Errorf("appendRows() = %v, want only the read habit", rows)
+		}
+	})
+}
@@ -0,0 +1,338 @@
+package sheets
+
+import (
+	"fmt"
+	"habitsSync/internal/domain"
+	"strings"
+	"time"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// headerRow is the row where the table starts. The first cell of the first
+// row is the title with the metadata of the import, the rest of the row is
+// left for whoever wants to write on it
+const headerRow = 1
+
+// cellFields are the fields of a cell owned by the imports. Anything else is
+// left untouched
+const cellFields = "userEnteredValue,userEnteredFormat(backgroundColor,textFormat(foregroundColor,bold),numberFormat)"
+
+// anchor is the cell where the header of a table is written: below the title,
+// or wherever a template says
+type anchor struct {
+	row, column int
+	// columns are the columns of the Sheet where each column of the table is,
+	// when they are not next to each other, as on merged Sheets
+	columns []int
+}
+
+// col is the column of the Sheet where the column i of the table is
+func (a anchor) col(i int) int {
+	if i < len(a.columns) {
+		return a.columns[i]
+	}
+	return a.column + i
+}
+
+// width is the number of columns of the Sheet up to the last one of a table
+// with the given columns
+func (a anchor) width(columns int) int {
+	w := a.column + columns
+	for _, c := range a.columns {
+		if c+1 > w {
+			w = c + 1
+		}
+	}
+	return w
+}
+
+// runs splits the columns of the table in the runs of columns that are next to
+// each other on the Sheet, as [start, end) indexes of the table
+func (a anchor) runs(start, end int) [][2]int {
+	runs := make([][2]int, 0, 1)
+	for i := start; i < end; i++ {
+		if n := len(runs); n != 0 && a.col(i) == a.col(i-1)+1 {
+			runs[n-1][1] = i + 1
+			continue
+		}
+		runs = append(runs, [2]int{i, i + 1})
+	}
+	return runs
+}
+
+var defaultAnchor = anchor{row: headerRow}
+
+// serialEpoch is the day 0 of the dates on a spreadsheet
+var serialEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// clearSheet clears the Sheet from the header on. The title is overwritten by
+// writeTitle, so the first row is kept
+func clearSheet(sheetID int64) *sheets.Request {
+	return &sheets.Request{
+		UpdateCells: &sheets.UpdateCellsRequest{
+			Range:  &sheets.GridRange{SheetId: sheetID, StartRowIndex: headerRow},
+			Fields: cellFields,
+		},
+	}
+}
+
+// growGrid makes room for the rows and columns to be written, since cells
+// cannot be updated outside the grid of the Sheet. Returns nil if they fit
+func growGrid(sheet *sheets.SheetProperties, rows, columns int) *sheets.Request {
+	grid := sheet.GridProperties
+	if grid == nil || (grid.RowCount >= int64(rows) && grid.ColumnCount >= int64(columns)) {
+		return nil
+	}
+
+	properties := &sheets.SheetProperties{
+		SheetId: sheet.SheetId,
+		GridProperties: &sheets.GridProperties{
+			RowCount:    max64(grid.RowCount, int64(rows)),
+			ColumnCount: max64(grid.ColumnCount, int64(columns)),
+		},
+	}
+	return &sheets.Request{
+		UpdateSheetProperties: &sheets.UpdateSheetPropertiesRequest{
+			Properties: properties,
+			Fields:     "gridProperties(rowCount,columnCount)",
+		},
+	}
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// writeTitle sets the title of the table on the first cell of the Sheet
+func writeTitle(sheetID int64, title string) *sheets.Request {
+	return &sheets.Request{
+		UpdateCells: &sheets.UpdateCellsRequest{
+			Start:  &sheets.GridCoordinate{SheetId: sheetID},
+			Rows:   []*sheets.RowData{rowData([]interface{}{title}, nil)},
+			Fields: "userEnteredValue",
+		},
+	}
+}
+
+// formatRequests freeze the header of the table at the anchor, make it bold
+// and format its columns down to the end of the Sheet. Rows written by the
+// cells are formatted already, but not the ones appended as values, nor the
+// header of a Sheet that existed before the first import
+func formatRequests(sheetID int64, table domain.Table, a anchor) []*sheets.Request {
+	requests := []*sheets.Request{{
+		UpdateSheetProperties: &sheets.UpdateSheetPropertiesRequest{
+			Properties: &sheets.SheetProperties{
+				SheetId:        sheetID,
+				GridProperties: &sheets.GridProperties{FrozenRowCount: int64(a.row) + 1},
+			},
+			Fields: "gridProperties.frozenRowCount",
+		},
+	}}
+	for i, c := range table.Columns {
+		header := dataRange(sheetID, a, i)
+		header.StartRowIndex, header.EndRowIndex = int64(a.row), int64(a.row)+1
+		requests = append(requests, &sheets.Request{
+			RepeatCell: &sheets.RepeatCellRequest{
+				Range:  header,
+				Cell:   &sheets.CellData{UserEnteredFormat: &sheets.CellFormat{TextFormat: &sheets.TextFormat{Bold: true}}},
+				Fields: "userEnteredFormat.textFormat.bold",
+			},
+		})
+		if nf := numberFormat(c.Format, nil); nf != nil {
+			requests = append(requests, &sheets.Request{
+				RepeatCell: &sheets.RepeatCellRequest{
+					Range:  dataRange(sheetID, a, i),
+					Cell:   &sheets.CellData{UserEnteredFormat: &sheets.CellFormat{NumberFormat: nf}},
+					Fields: "userEnteredFormat.numberFormat",
+				},
+			})
+		}
+	}
+	return requests
+}
+
+// autoResize fits the width of the columns of the table to their content.
+// The title must be written afterwards, or the first column would be as wide
+func autoResize(sheetID int64, columns int) *sheets.Request {
+	return &sheets.Request{
+		AutoResizeDimensions: &sheets.AutoResizeDimensionsRequest{
+			Dimensions: &sheets.DimensionRange{
+				SheetId:   sheetID,
+				Dimension: "COLUMNS",
+				EndIndex:  int64(columns),
+			},
+		},
+	}
+}
+
+// readTable is the opposite of rowsData: the table on the values of a Sheet,
+// whose header is at the anchor
+func readTable(values [][]interface{}, a anchor) domain.Table {
+	t := domain.Table{Rows: make([][]interface{}, 0)}
+	if len(values) <= a.row {
+		return t
+	}
+
+	cells := func(row []interface{}) []interface{} {
+		if len(row) <= a.column {
+			return make([]interface{}, 0)
+		}
+		return row[a.column:]
+	}
+	for _, v := range cells(values[a.row]) {
+		t.Columns = append(t.Columns, domain.Column{Name: fmt.Sprint(v)})
+	}
+	for _, row := range values[a.row+1:] {
+		t.Rows = append(t.Rows, cells(row))
+	}
+	return t
+}
+
+func rowsData(table domain.Table) []*sheets.RowData {
+	rows := make([]*sheets.RowData, 0, len(table.Rows)+1)
+	rows = append(rows, headerData(table))
+	for _, row := range table.Rows {
+		rows = append(rows, rowData(row, table.Columns))
+	}
+	return rows
+}
+
+func headerData(table domain.Table) *sheets.RowData {
+	row := rowData(table.Header(), nil)
+	for _, c := range row.Values {
+		c.UserEnteredFormat = &sheets.CellFormat{TextFormat: &sheets.TextFormat{Bold: true}}
+	}
+	return row
+}
+
+func rowData(values []interface{}, columns []domain.Column) *sheets.RowData {
+	row := &sheets.RowData{Values: make([]*sheets.CellData, 0, len(values))}
+	for i, v := range values {
+		format := domain.PlainFormat
+		if i < len(columns) {
+			format = columns[i].Format
+		}
+		row.Values = append(row.Values, cellData(v, format))
+	}
+	return row
+}
+
+func cellData(v interface{}, format domain.Format) *sheets.CellData {
+	cell := &sheets.CellData{
+		UserEnteredValue: extendedValue(domain.Value(v)),
+	}
+
+	if nf := numberFormat(format, domain.Value(v)); nf != nil {
+		cell.UserEnteredFormat = &sheets.CellFormat{NumberFormat: nf}
+	}
+
+	if c, ok := v.(domain.Cell); ok {
+		if cell.UserEnteredFormat == nil {
+			cell.UserEnteredFormat = &sheets.CellFormat{}
+		}
+		foreground := &sheets.Color{}
+		if c.Background.Dark() {
+			foreground = &sheets.Color{Red: 1, Green: 1, Blue: 1}
+		}
+		cell.UserEnteredFormat.BackgroundColor = color(c.Background)
+		cell.UserEnteredFormat.TextFormat = &sheets.TextFormat{ForegroundColor: foreground}
+	}
+
+	return cell
+}
+
+// numberFormat returns the format of the column, or the one of the value when
+// the column has none. Nil when the value is shown as it is
+func numberFormat(format domain.Format, v interface{}) *sheets.NumberFormat {
+	if t, ok := v.(time.Time); ok && format == domain.PlainFormat {
+		format = domain.DateFormat
+		if !isDate(t) {
+			format = domain.DateTimeFormat
+		}
+	}
+
+	switch format {
+	case domain.PercentFormat:
+		return &sheets.NumberFormat{Type: "PERCENT", Pattern: "0.0%"}
+	case domain.DateFormat:
+		return &sheets.NumberFormat{Type: "DATE", Pattern: "yyyy-mm-dd"}
+	case domain.DateTimeFormat:
+		return &sheets.NumberFormat{Type: "DATE_TIME", Pattern: "yyyy-mm-dd hh:mm:ss"}
+	}
+	return nil
+}
+
+// extendedValue types the value of a cell. Strings are written as they are,
+// unless they are formulas
+func extendedValue(v interface{}) *sheets.ExtendedValue {
+	number := func(f float64) *sheets.ExtendedValue {
+		return &sheets.ExtendedValue{NumberValue: &f}
+	}
+
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case int:
+		return number(float64(v))
+	case int64:
+		return number(float64(v))
+	case float64:
+		return number(v)
+	case bool:
+		return &sheets.ExtendedValue{BoolValue: &v}
+	case time.Time:
+		return number(serial(v))
+	case string:
+		if v == "" {
+			return nil
+		}
+		if strings.HasPrefix(v, "=") {
+			return &sheets.ExtendedValue{FormulaValue: &v}
+		}
+		return &sheets.ExtendedValue{StringValue: &v}
+	}
+
+	s := fmt.Sprint(v)
+	return &sheets.ExtendedValue{StringValue: &s}
+}
+
+// serial is the number of days since the epoch of spreadsheet dates. Dates on
+// a spreadsheet have no time zone, so the wall clock of t is used
+func serial(t time.Time) float64 {
+	y, m, d := t.Date()
+	wall := time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	return float64(wall.Sub(serialEpoch)) / float64(24*time.Hour)
+}
+
+func isDate(t time.Time) bool {
+	return t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0
+}
+
+// userEnteredValues formats the values of a row the way a user would type
+// them, for the APIs that do not take typed cells
+func userEnteredValues(row []interface{}) []interface{} {
+	values := make([]interface{}, 0, len(row))
+	for _, v := range row {
+		v = domain.Value(v)
+		if t, ok := v.(time.Time); ok {
+			v = t.Format("2006-01-02 15:04:05")
+			if isDate(t) {
+				v = t.Format("2006-01-02")
+			}
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+func color(c domain.Color) *sheets.Color {
+	return &sheets.Color{
+		Red:   float64(c.R) / 255,
+		Green: float64(c.G) / 255,
+		Blue:  float64(c.B) / 255,
+	}
+}
@@ -0,0 +1,104 @@
+package sheets
+
+import (
+	"habitsSync/internal/domain"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCellData(t *testing.T) {
+	day := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		value      interface{}
+		format     domain.Format
+		wantNumber float64
+		wantFormat string
+	}{
+		{name: "number", value: 10, wantNumber: 10},
+		{name: "percent", value: 0.25, format: domain.PercentFormat, wantNumber: 0.25, wantFormat: "PERCENT"},
+		{name: "date", value: day, wantNumber: 44256, wantFormat: "DATE"},
+		{name: "date and time", value: day.Add(12 * time.Hour), wantNumber: 44256.5, wantFormat: "DATE_TIME"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cell := cellData(tt.value, tt.format)
+			if got := *cell.UserEnteredValue.NumberValue; got != tt.wantNumber {
+				t.Errorf("cellData() number = %v, want %v", got, tt.wantNumber)
+			}
+			gotFormat := ""
+			if cell.UserEnteredFormat != nil && cell.UserEnteredFormat.NumberFormat != nil {
+				gotFormat = cell.UserEnteredFormat.NumberFormat.Type
+			}
+			if gotFormat != tt.wantFormat {
+				t.Errorf("cellData() format = %q, want %q", gotFormat, tt.wantFormat)
+			}
+		})
+	}
+}
+
+func TestCellData_strings(t *testing.T) {
+	if cell := cellData("", domain.PlainFormat); cell.UserEnteredValue != nil {
+		t.Errorf("cellData() of an empty string = %v, want an empty cell", cell.UserEnteredValue)
+	}
+	if cell := cellData("=SUM(C3:C10)", domain.PlainFormat); cell.UserEnteredValue.FormulaValue == nil {
+		t.Error("cellData() did not write the formula as a formula")
+	}
+	if cell := cellData("1/2", domain.PlainFormat); *cell.UserEnteredValue.StringValue != "1/2" {
+		t.Error("cellData() did not write the string as it is")
+	}
+}
+
+func TestReadTable(t *testing.T) {
+	values := [][]interface{}{
+		{"Imported from backup"},
+		{"", "ID", "Name"},
+		{"", 1.0, "run"},
+		{},
+	}
+
+	got := readTable(values, anchor{row: 1, column: 1})
+	want := domain.Table{
+		Columns: []domain.Column{{Name: "ID"}, {Name: "Name"}},
+		Rows:    [][]interface{}{{1.0, "run"}, {}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readTable() = %+v, want %+v", got, want)
+	}
+
+	if got := readTable(values[:1], defaultAnchor); len(got.Columns) != 0 || len(got.Rows) != 0 {
+		t.Errorf("readTable() of an empty Sheet = %+v, want an empty table", got)
+	}
+}
+
+func TestClearSheet(t *testing.T) {
+	r := clearSheet(3).UpdateCells.Range
+	if r.SheetId != 3 || r.StartRowIndex != headerRow || r.EndRowIndex != 0 {
+		t.Errorf("clearSheet() range = %+v, want the Sheet from the header on", r)
+	}
+}
+
+func TestFormatRequests(t *testing.T) {
+	table := domain.Table{Columns: []domain.Column{{Name: "Date", Format: domain.DateFormat}, {Name: "Habit"}}}
+
+	requests := formatRequests(3, table, anchor{row: headerRow, columns: []int{4, 1}})
+
+	if got := requests[0].UpdateSheetProperties.Properties.GridProperties.FrozenRowCount; got != headerRow+1 {
+		t.Errorf("formatRequests() froze %v rows, want %v", got, headerRow+1)
+	}
+	var bold, formatted []int64
+	for _, r := range requests[1:] {
+		if r.RepeatCell.Fields == "userEnteredFormat.textFormat.bold" {
+			bold = append(bold, r.RepeatCell.Range.StartColumnIndex)
+			continue
+		}
+		formatted = append(formatted, r.RepeatCell.Range.StartColumnIndex)
+	}
+	if want := []int64{4, 1}; !reflect.DeepEqual(bold, want) {
+		t.Errorf("formatRequests() made bold the header of columns %v, want %v", bold, want)
+	}
+	if want := []int64{4}; !reflect.DeepEqual(formatted, want) {
+		t.Errorf("formatRequests() formatted columns %v, want %v", formatted, want)
+	}
+}
@@ -0,0 +1,250 @@
+package sheets
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"habitsSync/internal/domain"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+var (
+	red    = &sheets.Color{Red: 0.9, Green: 0.49, Blue: 0.45}
+	yellow = &sheets.Color{Red: 1, Green: 0.84, Blue: 0.4}
+	green  = &sheets.Color{Red: 0.34, Green: 0.73, Blue: 0.54}
+)
+
+// decorationsKey is the developer metadata telling what a previous import
+// added to the Sheet, as rules and charts cannot be tagged themselves
+const decorationsKey = "habitsSync.decorations"
+
+// decorationsMetadata are the rules, the first ones of the Sheet, and the
+// charts added by the last import
+type decorationsMetadata struct {
+	Rules  int     `json:"rules"`
+	Charts []int64 `json:"charts,omitempty"`
+}
+
+// decorations adds the conditional formatting and the chart of the table
+// written at the anchor of the Sheet, whose data ends on the row index rows.
+// The rules and charts left by a previous import are replaced, even when the
+// table has none, and anything else made by hand is kept
+func decorations(sheet *sheets.Sheet, table domain.Table, a anchor, rows int) ([]*sheets.Request, error) {
+	sheetID := sheet.Properties.SheetId
+	requests := make([]*sheets.Request, 0)
+
+	previous, metadataID, err := decorationsOf(sheet)
+	if err != nil {
+		return nil, err
+	}
+	// Backwards, so the index of the remaining rules do not change
+	for i := min(previous.Rules, len(sheet.ConditionalFormats)) - 1; i >= 0; i-- {
+		requests = append(requests, &sheets.Request{
+			DeleteConditionalFormatRule: &sheets.DeleteConditionalFormatRuleRequest{
+				SheetId: sheetID,
+				Index:   int64(i),
+			},
+		})
+	}
+	charts := make(map[int64]bool, len(sheet.Charts))
+	for _, c := range sheet.Charts {
+		charts[c.ChartId] = true
+	}
+	for _, id := range previous.Charts {
+		if charts[id] {
+			requests = append(requests, &sheets.Request{
+				DeleteEmbeddedObject: &sheets.DeleteEmbeddedObjectRequest{ObjectId: id},
+			})
+		}
+	}
+
+	added := decorationsMetadata{}
+	for i, c := range table.Columns {
+		for _, rule := range highlightRules(dataRange(sheetID, a, i), c.Highlight) {
+			// The imported rules go first, where the next import finds them
+			rule.AddConditionalFormatRule.Index = int64(added.Rules)
+			rule.AddConditionalFormatRule.ForceSendFields = []string{"Index"}
+			requests = append(requests, rule)
+			added.Rules++
+		}
+	}
+	if table.Chart != nil {
+		id, err := newObjectID()
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, addChart(sheetID, id, *table.Chart, a, len(table.Columns), rows))
+		added.Charts = append(added.Charts, id)
+	}
+
+	if metadataID != 0 {
+		requests = append(requests, &sheets.Request{
+			DeleteDeveloperMetadata: &sheets.DeleteDeveloperMetadataRequest{
+				DataFilter: &sheets.DataFilter{
+					DeveloperMetadataLookup: &sheets.DeveloperMetadataLookup{MetadataId: metadataID},
+				},
+			},
+		})
+	}
+	if added.Rules != 0 || len(added.Charts) != 0 {
+		value, err := json.Marshal(added)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, sheetMetadataRequest(sheetID, decorationsKey, string(value)))
+	}
+
+	return requests, nil
+}
+
+// decorationsOf returns what the last import added to the Sheet, and the ID of
+// the metadata telling it. Zero when nothing was added
+func decorationsOf(sheet *sheets.Sheet) (decorationsMetadata, int64, error) {
+	d := decorationsMetadata{}
+	for _, m := range sheet.DeveloperMetadata {
+		if m.MetadataKey != decorationsKey {
+			continue
+		}
+		if err := json.Unmarshal([]byte(m.MetadataValue), &d); err != nil {
+			return d, 0, fmt.Errorf("invalid decorations metadata on %v: %w", sheet.Properties.Title, err)
+		}
+		return d, m.MetadataId, nil
+	}
+	return d, 0, nil
+}
+
+// newObjectID returns a random ID for a chart, so it is known before the chart
+// is added. IDs are positive 32 bits integers
+func newObjectID() (int64, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint32(b)>>1) + 1, nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// dataRange is the column of the table below its header, up to the end of the
+// Sheet, so the rules follow the rows added by hand or by other imports
+func dataRange(sheetID int64, a anchor, column int) *sheets.GridRange {
+	return &sheets.GridRange{
+		SheetId:          sheetID,
+		StartRowIndex:    int64(a.row) + 1,
+		StartColumnIndex: int64(a.col(column)),
+		EndColumnIndex:   int64(a.col(column)) + 1,
+	}
+}
+
+func highlightRules(r *sheets.GridRange, h domain.Highlight) []*sheets.Request {
+	switch h {
+	case domain.ScaleHighlight:
+		return []*sheets.Request{addRule(r, &sheets.ConditionalFormatRule{
+			GradientRule: &sheets.GradientRule{
+				Minpoint: &sheets.InterpolationPoint{Color: red, Type: "MIN"},
+				Midpoint: &sheets.InterpolationPoint{Color: yellow, Type: "PERCENTILE", Value: "50"},
+				Maxpoint: &sheets.InterpolationPoint{Color: green, Type: "MAX"},
+			},
+		})}
+	case domain.SignHighlight:
+		return []*sheets.Request{
+			addRule(r, booleanRule("NUMBER_GREATER", "0", green)),
+			addRule(r, booleanRule("NUMBER_LESS", "0", red)),
+		}
+	case domain.StatusHighlight:
+		return []*sheets.Request{
+			addRule(r, booleanRule("TEXT_EQ", domain.OnTrack, green)),
+			addRule(r, booleanRule("TEXT_EQ", domain.Behind, red)),
+		}
+	}
+	return nil
+}
+
+func booleanRule(condition string, value string, c *sheets.Color) *sheets.ConditionalFormatRule {
+	return &sheets.ConditionalFormatRule{
+		BooleanRule: &sheets.BooleanRule{
+			Condition: &sheets.BooleanCondition{
+				Type:   condition,
+				Values: []*sheets.ConditionValue{{UserEnteredValue: value}},
+			},
+			Format: &sheets.CellFormat{BackgroundColor: c},
+		},
+	}
+}
+
+func addRule(r *sheets.GridRange, rule *sheets.ConditionalFormatRule) *sheets.Request {
+	rule.Ranges = []*sheets.GridRange{r}
+	return &sheets.Request{
+		AddConditionalFormatRule: &sheets.AddConditionalFormatRuleRequest{Rule: rule},
+	}
+}
+
+// addChart places the chart at the right of the table
+func addChart(sheetID int64, chartID int64, chart domain.Chart, a anchor, columns int, rows int) *sheets.Request {
+	spec := &sheets.BasicChartSpec{
+		ChartType:      string(chart.Type),
+		LegendPosition: "BOTTOM_LEGEND",
+		HeaderCount:    1,
+	}
+
+	// A range per run of columns, as the columns of a merged table may be apart
+	source := func(startRow, endRow int64, startColumn, endColumn int) *sheets.ChartData {
+		sources := make([]*sheets.GridRange, 0, 1)
+		for _, run := range a.runs(startColumn, endColumn) {
+			sources = append(sources, &sheets.GridRange{
+				SheetId:          sheetID,
+				StartRowIndex:    startRow,
+				EndRowIndex:      endRow,
+				StartColumnIndex: int64(a.col(run[0])),
+				EndColumnIndex:   int64(a.col(run[1]-1)) + 1,
+			})
+		}
+		return &sheets.ChartData{SourceRange: &sheets.ChartSourceRange{Sources: sources}}
+	}
+
+	if chart.ByRow && len(chart.Series) != 0 {
+		// The label of every row is its Domain column, right before the Series
+		end := chart.Series[len(chart.Series)-1] + 1
+		spec.Domains = []*sheets.BasicChartDomain{{Domain: source(int64(a.row), int64(a.row)+1, chart.Domain, end)}}
+		for r := int64(a.row) + 1; r < int64(rows); r++ {
+			spec.Series = append(spec.Series, &sheets.BasicChartSeries{Series: source(r, r+1, chart.Domain, end)})
+		}
+	} else {
+		spec.Domains = []*sheets.BasicChartDomain{{Domain: source(int64(a.row), int64(rows), chart.Domain, chart.Domain+1)}}
+		for _, s := range chart.Series {
+			spec.Series = append(spec.Series, &sheets.BasicChartSeries{Series: source(int64(a.row), int64(rows), s, s+1)})
+		}
+	}
+
+	return &sheets.Request{
+		AddChart: &sheets.AddChartRequest{
+			Chart: &sheets.EmbeddedChart{
+				ChartId: chartID,
+				Spec:    &sheets.ChartSpec{Title: chart.Title, BasicChart: spec},
+				Position: &sheets.EmbeddedObjectPosition{
+					OverlayPosition: &sheets.OverlayPosition{
+						AnchorCell: &sheets.GridCoordinate{
+							SheetId:     sheetID,
+							RowIndex:    int64(a.row),
+							ColumnIndex: int64(a.width(columns)) + 1,
+						},
+					},
+				},
+			},
+		},
+	}
+}
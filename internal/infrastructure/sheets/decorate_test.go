@@ -0,0 +1,143 @@
+package sheets
+
+import (
+	"fmt"
+	"habitsSync/internal/domain"
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+func TestDecorations(t *testing.T) {
+	table := domain.ComparisonTable([]domain.HabitComparison{
+		{Habit: domain.Habit{ID: 1, Name: "run", Count: 5}, Previous: 3},
+		{Habit: domain.Habit{ID: 2, Name: "read", Count: 1}, Previous: 2},
+	})
+	sheet := &sheets.Sheet{
+		Properties: &sheets.SheetProperties{SheetId: 42},
+		ConditionalFormats: []*sheets.ConditionalFormatRule{
+			{Ranges: []*sheets.GridRange{dataRange(42, defaultAnchor, 4)}}, // Delta, by a previous import
+			{Ranges: []*sheets.GridRange{dataRange(42, defaultAnchor, 3)}}, // Previous, by an import of another layout
+			{Ranges: []*sheets.GridRange{dataRange(42, defaultAnchor, 4)}}, // by hand, on the same range
+		},
+		Charts: []*sheets.EmbeddedChart{
+			{ChartId: 7, Spec: &sheets.ChartSpec{Title: table.Chart.Title}},
+			{ChartId: 8, Spec: &sheets.ChartSpec{Title: table.Chart.Title}}, // by hand, with the same title
+		},
+		DeveloperMetadata: []*sheets.DeveloperMetadata{
+			{MetadataId: 5, MetadataKey: decorationsKey, MetadataValue: `{"rules":2,"charts":[7]}`},
+		},
+	}
+
+	requests, err := decorations(sheet, table, defaultAnchor, 4)
+	if err != nil {
+		t.Fatalf("decorations() error = %v", err)
+	}
+
+	deleted := make([]int64, 0)
+	rules := make([]int64, 0)
+	var chartID int64
+	var metadata *sheets.DeveloperMetadata
+	for _, r := range requests {
+		switch {
+		case r.DeleteConditionalFormatRule != nil:
+			deleted = append(deleted, r.DeleteConditionalFormatRule.Index)
+		case r.AddConditionalFormatRule != nil:
+			rules = append(rules, r.AddConditionalFormatRule.Index)
+		case r.DeleteEmbeddedObject != nil:
+			if r.DeleteEmbeddedObject.ObjectId != 7 {
+				t.Errorf("decorations() deleted chart %v, want 7", r.DeleteEmbeddedObject.ObjectId)
+			}
+		case r.AddChart != nil:
+			chartID = r.AddChart.Chart.ChartId
+			spec := r.AddChart.Chart.Spec.BasicChart
+			if len(spec.Series) != 2 || spec.Series[0].Series.SourceRange.Sources[0].EndRowIndex != 4 {
+				t.Errorf("decorations() chart series = %v, want Count and Previous up to row 4", spec.Series)
+			}
+		case r.DeleteDeveloperMetadata != nil:
+			if id := r.DeleteDeveloperMetadata.DataFilter.DeveloperMetadataLookup.MetadataId; id != 5 {
+				t.Errorf("decorations() deleted metadata %v, want 5", id)
+			}
+		case r.CreateDeveloperMetadata != nil:
+			metadata = r.CreateDeveloperMetadata.DeveloperMetadata
+		}
+	}
+
+	if !reflect.DeepEqual(deleted, []int64{1, 0}) {
+		t.Errorf("decorations() deleted rules %v, want [1 0]", deleted)
+	}
+	if want := []int64{0, 1, 2, 3, 4, 5}; !reflect.DeepEqual(rules, want) {
+		t.Errorf("decorations() added rules at %v, want %v for Delta, Change and Status", rules, want)
+	}
+	if chartID <= 0 {
+		t.Fatalf("decorations() added chart %v, want a positive ID", chartID)
+	}
+	if want := fmt.Sprintf(`{"rules":6,"charts":[%v]}`, chartID); metadata == nil || metadata.MetadataValue != want {
+		t.Errorf("decorations() metadata = %+v, want %v", metadata, want)
+	}
+}
+
+func TestDecorations_optionsOff(t *testing.T) {
+	table := domain.HabitsTable([]domain.Habit{{ID: 1, Name: "run", Count: 5}}).Plain()
+	table.Chart = nil
+	sheet := &sheets.Sheet{
+		Properties:         &sheets.SheetProperties{SheetId: 42},
+		ConditionalFormats: []*sheets.ConditionalFormatRule{{}, {}},
+		Charts:             []*sheets.EmbeddedChart{{ChartId: 7}},
+		DeveloperMetadata: []*sheets.DeveloperMetadata{
+			{MetadataId: 5, MetadataKey: decorationsKey, MetadataValue: `{"rules":1,"charts":[7]}`},
+		},
+	}
+
+	requests, err := decorations(sheet, table, defaultAnchor, 3)
+	if err != nil {
+		t.Fatalf("decorations() error = %v", err)
+	}
+
+	want := []string{"delete rule 0", "delete chart 7", "delete metadata 5"}
+	got := make([]string, 0, len(requests))
+	for _, r := range requests {
+		switch {
+		case r.DeleteConditionalFormatRule != nil:
+			got = append(got, fmt.Sprintf("delete rule %v", r.DeleteConditionalFormatRule.Index))
+		case r.DeleteEmbeddedObject != nil:
+			got = append(got, fmt.Sprintf("delete chart %v", r.DeleteEmbeddedObject.ObjectId))
+		case r.DeleteDeveloperMetadata != nil:
+			got = append(got, fmt.Sprintf("delete metadata %v", r.DeleteDeveloperMetadata.DataFilter.DeveloperMetadataLookup.MetadataId))
+		default:
+			got = append(got, "other")
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decorations() = %v, want %v", got, want)
+	}
+}
+
+func TestDecorations_byRow(t *testing.T) {
+	table := domain.WeekdaysTable(domain.WeekdaysReport{Habits: []domain.HabitWeekdays{
+		{ID: 1, Name: "run"}, {ID: 2, Name: "read"}, {ID: 3, Name: "walk"},
+	}})
+	sheet := &sheets.Sheet{Properties: &sheets.SheetProperties{SheetId: 42}}
+
+	requests, err := decorations(sheet, table, defaultAnchor, headerRow+1+len(table.Rows))
+	if err != nil {
+		t.Fatalf("decorations() error = %v", err)
+	}
+
+	chart := requests[len(requests)-2].AddChart
+	if chart == nil {
+		t.Fatal("decorations() did not add the chart before the metadata")
+	}
+	spec := chart.Chart.Spec.BasicChart
+	if spec.ChartType != "LINE" || len(spec.Series) != 3 {
+		t.Fatalf("decorations() = %v chart with %v series, want LINE with one per habit", spec.ChartType, len(spec.Series))
+	}
+	walk := spec.Series[2].Series.SourceRange.Sources[0]
+	if walk.StartRowIndex != 4 || walk.StartColumnIndex != 1 || walk.EndColumnIndex != 9 {
+		t.Errorf("decorations() walk series = %+v, want row 4 from Name to Sun", walk)
+	}
+	if len(requests) != 9 {
+		t.Errorf("decorations() = %v requests, want a rule for each weekday rate, the chart and the metadata", len(requests))
+	}
+}
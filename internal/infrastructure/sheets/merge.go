@@ -0,0 +1,215 @@
+package sheets
+
+import (
+	"fmt"
+	"habitsSync/internal/domain"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// mergeFields are the fields of a cell owned by a merge. Besides the ones
+// owned by an import, merges mark the rows that are no longer imported
+const mergeFields = "userEnteredValue,userEnteredFormat(backgroundColor,textFormat(foregroundColor,bold,strikethrough),numberFormat),note"
+
+const missingNote = "Not found on the last import"
+
+// mergeRequests writes the rows of the table over the existing rows of the
+// Sheet with the same key, touching only the columns of the table. Columns are
+// found by the name on the header, and the ones missing are added after the
+// last column of the Sheet. Rows of the Sheet no longer in the table are
+// struck through, and new rows are written after the last row of the Sheet.
+// The existing rows start at the header. Returns the requests, the anchor with
+// the columns where the table is written, the row index where each row of the
+// table is written and the number of rows the Sheet needs
+func mergeRequests(sheetID int64, existing [][]interface{}, table domain.Table) ([]*sheets.Request, anchor, []int, int) {
+	a := mergeAnchor(existing, table)
+
+	key := table.KeyColumns()
+	sheetKey := make([]int, 0, len(key))
+	for _, c := range key {
+		sheetKey = append(sheetKey, a.col(c))
+	}
+	index := make(map[string]int, len(table.Rows))
+	for i, row := range table.Rows {
+		index[rowKey(row, key)] = i
+	}
+
+	requests := writeRow(sheetID, headerRow, a, headerData(table))
+	written := make(map[int]bool, len(table.Rows))
+	positions := make([]int, len(table.Rows))
+	last := headerRow
+	for i := 1; i < len(existing); i++ {
+		rowIndex := headerRow + i
+		if isEmptyRow(existing[i]) {
+			continue
+		}
+		last = rowIndex
+
+		k := rowKey(existing[i], sheetKey)
+		if k == "" {
+			continue // Rows without a key belong to the user
+		}
+		j, ok := index[k]
+		if !ok {
+			requests = append(requests, markMissing(sheetID, rowIndex, a.col(key[0]), a.width(len(table.Columns)))...)
+			continue
+		}
+		written[j] = true
+		positions[j] = rowIndex
+		requests = append(requests, writeRow(sheetID, rowIndex, a, rowData(table.Rows[j], table.Columns))...)
+	}
+
+	next := last + 1
+	for j, row := range table.Rows {
+		if written[j] {
+			continue
+		}
+		requests = append(requests, writeRow(sheetID, next, a, rowData(row, table.Columns))...)
+		positions[j] = next
+		next++
+	}
+
+	return requests, a, positions, next
+}
+
+// mergeAnchor finds the columns of the table on the header of the Sheet.
+// The ones not found go after the last column with a value on any row
+func mergeAnchor(existing [][]interface{}, table domain.Table) anchor {
+	found := make(map[string]int)
+	last := -1
+	for i, row := range existing {
+		for j, v := range row {
+			if keyString(domain.Value(v)) == "" {
+				continue
+			}
+			if j > last {
+				last = j
+			}
+			if name := fmt.Sprint(v); i == 0 {
+				if _, ok := found[name]; !ok {
+					found[name] = j
+				}
+			}
+		}
+	}
+
+	a := anchor{row: headerRow, columns: make([]int, len(table.Columns))}
+	for i, c := range table.Columns {
+		j, ok := found[c.Name]
+		if !ok {
+			last++
+			j = last
+		}
+		a.columns[i] = j
+	}
+	return a
+}
+
+// writeRow writes the cells of a row of the table on the columns of the
+// anchor, a request per run of columns, so the columns between are kept
+func writeRow(sheetID int64, rowIndex int, a anchor, row *sheets.RowData) []*sheets.Request {
+	requests := make([]*sheets.Request, 0, 1)
+	for _, run := range a.runs(0, len(row.Values)) {
+		requests = append(requests, &sheets.Request{
+			UpdateCells: &sheets.UpdateCellsRequest{
+				Start: &sheets.GridCoordinate{
+					SheetId:     sheetID,
+					RowIndex:    int64(rowIndex),
+					ColumnIndex: int64(a.col(run[0])),
+				},
+				Rows:   []*sheets.RowData{{Values: row.Values[run[0]:run[1]]}},
+				Fields: mergeFields,
+			},
+		})
+	}
+	return requests
+}
+
+func markMissing(sheetID int64, rowIndex int, keyColumn int, width int) []*sheets.Request {
+	strike := &sheets.Request{
+		RepeatCell: &sheets.RepeatCellRequest{
+			Range: &sheets.GridRange{
+				SheetId:        sheetID,
+				StartRowIndex:  int64(rowIndex),
+				EndRowIndex:    int64(rowIndex + 1),
+				EndColumnIndex: int64(width),
+			},
+			Cell: &sheets.CellData{
+				UserEnteredFormat: &sheets.CellFormat{
+					TextFormat: &sheets.TextFormat{Strikethrough: true},
+				},
+			},
+			Fields: "userEnteredFormat.textFormat.strikethrough",
+		},
+	}
+	note := &sheets.Request{
+		UpdateCells: &sheets.UpdateCellsRequest{
+			Start: &sheets.GridCoordinate{
+				SheetId:     sheetID,
+				RowIndex:    int64(rowIndex),
+				ColumnIndex: int64(keyColumn),
+			},
+			Rows:   []*sheets.RowData{{Values: []*sheets.CellData{{Note: missingNote}}}},
+			Fields: "note",
+		},
+	}
+	return []*sheets.Request{strike, note}
+}
+
+func isEmptyRow(row []interface{}) bool {
+	for _, v := range row {
+		if keyString(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// rowKey identifies a row, both for the values of a Table and for the
+// unformatted values read from a Sheet. Empty when all the key cells are
+func rowKey(row []interface{}, key []int) string {
+	parts := make([]string, 0, len(key))
+	empty := true
+	for _, c := range key {
+		var v interface{}
+		if c < len(row) {
+			v = domain.Value(row[c])
+		}
+		s := keyString(v)
+		empty = empty && s == ""
+		parts = append(parts, s)
+	}
+	if empty {
+		return ""
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// keyString writes numbers and dates the way they are read unformatted from a
+// Sheet: as numbers
+func keyString(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		if v == math.Trunc(v) {
+			return strconv.FormatInt(int64(v), 10)
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case time.Time:
+		return keyString(serial(v))
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
@@ -0,0 +1,122 @@
+package sheets
+
+import (
+	"fmt"
+	"habitsSync/internal/domain"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+func TestMergeRequests(t *testing.T) {
+	existing := [][]interface{}{
+		{"ID", "Name", "Count", "Question", "Description", "Owner"},
+		{1.0, "run", 5.0, "", "", "Anna"},
+		{2.0, "read", 3.0, "", "", "Bob"},
+		{},
+		{"", "", "", "", "", "total"},
+		{9.0, "walk", 1.0},
+	}
+	table := domain.HabitsTable([]domain.Habit{
+		{ID: 3, Name: "meditate", Count: 1},
+		{ID: 1, Name: "run", Count: 7},
+	})
+
+	requests, _, positions, rows := mergeRequests(42, existing, table)
+
+	want := []string{
+		"write row 1",  // header
+		"write row 2",  // run
+		"strike row 3", // read
+		"note row 3",
+		"strike row 6", // walk
+		"note row 6",
+		"write row 7", // meditate
+	}
+	if got := describe(requests); !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeRequests() = %v, want %v", got, want)
+	}
+	if rows != 8 {
+		t.Errorf("mergeRequests() needs %v rows, want 8", rows)
+	}
+	if want := []int{7, 2}; !reflect.DeepEqual(positions, want) {
+		t.Errorf("mergeRequests() wrote the rows at %v, want %v", positions, want)
+	}
+
+	run := requests[1].UpdateCells.Rows[0].Values
+	if len(run) != len(table.Columns) || *run[2].UserEnteredValue.NumberValue != 7 {
+		t.Errorf("mergeRequests() wrote %v cells of run, want the %v of the table", len(run), len(table.Columns))
+	}
+}
+
+func TestMergeRequests_emptySheet(t *testing.T) {
+	table := domain.HabitsTable([]domain.Habit{{ID: 1, Name: "run", Count: 7}})
+
+	requests, _, _, rows := mergeRequests(42, nil, table)
+
+	want := []string{"write row 1", "write row 2"}
+	if got := describe(requests); !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeRequests() = %v, want %v", got, want)
+	}
+	if rows != 3 {
+		t.Errorf("mergeRequests() needs %v rows, want 3", rows)
+	}
+}
+
+func TestMergeRequests_columnsByName(t *testing.T) {
+	existing := [][]interface{}{
+		{"Name", "ID", "Owner", "Count"},
+		{"run", 1.0, "Anna", 5.0},
+		{"", "", "", "", "", "notes"},
+	}
+	table := domain.ComparisonTable([]domain.HabitComparison{
+		{Habit: domain.Habit{ID: 1, Name: "run", Count: 7}, Previous: 5},
+	})
+
+	requests, a, positions, _ := mergeRequests(42, existing, table)
+
+	// ID, Name, Count, Previous, Delta, Change and Status
+	if want := []int{1, 0, 3, 6, 7, 8, 9}; !reflect.DeepEqual(a.columns, want) {
+		t.Errorf("mergeRequests() columns = %v, want %v", a.columns, want)
+	}
+	if want := []int{2}; !reflect.DeepEqual(positions, want) {
+		t.Errorf("mergeRequests() wrote the rows at %v, want %v", positions, want)
+	}
+	for _, r := range requests {
+		start, cells := r.UpdateCells.Start.ColumnIndex, int64(len(r.UpdateCells.Rows[0].Values))
+		if start <= 2 && 2 < start+cells {
+			t.Errorf("mergeRequests() wrote over the Owner column of the user: %+v", r.UpdateCells.Start)
+		}
+	}
+}
+
+func TestRowKey(t *testing.T) {
+	day := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	key := []int{0, 1}
+
+	written := rowKey([]interface{}{day, domain.Cell{Value: "run"}}, key)
+	read := rowKey([]interface{}{44256.0, "run"}, key)
+	if written != read {
+		t.Errorf("rowKey() of the table = %q, of the sheet = %q", written, read)
+	}
+	if got := rowKey([]interface{}{"", nil, "notes"}, key); got != "" {
+		t.Errorf("rowKey() of an empty key = %q, want empty", got)
+	}
+}
+
+func describe(requests []*sheets.Request) []string {
+	got := make([]string, 0, len(requests))
+	for _, r := range requests {
+		switch {
+		case r.RepeatCell != nil:
+			got = append(got, fmt.Sprintf("strike row %v", r.RepeatCell.Range.StartRowIndex))
+		case r.UpdateCells != nil && r.UpdateCells.Fields == "note":
+			got = append(got, fmt.Sprintf("note row %v", r.UpdateCells.Start.RowIndex))
+		case r.UpdateCells != nil:
+			got = append(got, fmt.Sprintf("write row %v", r.UpdateCells.Start.RowIndex))
+		}
+	}
+	return got
+}
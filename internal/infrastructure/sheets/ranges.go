@@ -0,0 +1,71 @@
+package sheets
+
+import (
+	"habitsSync/internal/domain"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// namedRangeRequests points the named ranges of the table written at the
+// anchor to the rows where it has been written, on rows the row index of each
+// row of the table, with the data ending on the row index end. Named ranges of
+// the Sheet left by rows no longer in the table are deleted, so they do not
+// point to another habit
+func namedRangeRequests(existing []*sheets.NamedRange, sheetID int64, table domain.Table, a anchor, rows []int, end int) []*sheets.Request {
+	byName := make(map[string]*sheets.NamedRange, len(existing))
+	for _, r := range existing {
+		byName[r.Name] = r
+	}
+
+	requests := make([]*sheets.Request, 0)
+	wanted := make(map[string]bool)
+	for _, r := range table.NamedRanges() {
+		wanted[r.Name] = true
+		grid := &sheets.GridRange{
+			SheetId:          sheetID,
+			StartRowIndex:    int64(a.row) + 1,
+			EndRowIndex:      int64(end),
+			StartColumnIndex: int64(a.col(r.Column)),
+			EndColumnIndex:   int64(a.col(r.Column)) + 1,
+		}
+		if r.Row != -1 {
+			grid.StartRowIndex = int64(rows[r.Row])
+			grid.EndRowIndex = grid.StartRowIndex + 1
+		}
+
+		if e, ok := byName[r.Name]; ok {
+			requests = append(requests, &sheets.Request{
+				UpdateNamedRange: &sheets.UpdateNamedRangeRequest{
+					NamedRange: &sheets.NamedRange{NamedRangeId: e.NamedRangeId, Name: r.Name, Range: grid},
+					Fields:     "range",
+				},
+			})
+			continue
+		}
+		requests = append(requests, &sheets.Request{
+			AddNamedRange: &sheets.AddNamedRangeRequest{
+				NamedRange: &sheets.NamedRange{Name: r.Name, Range: grid},
+			},
+		})
+	}
+
+	for _, e := range existing {
+		if wanted[e.Name] || e.Range == nil || e.Range.SheetId != sheetID || !table.IsRowRange(e.Name) {
+			continue
+		}
+		requests = append(requests, &sheets.Request{
+			DeleteNamedRange: &sheets.DeleteNamedRangeRequest{NamedRangeId: e.NamedRangeId},
+		})
+	}
+
+	return requests
+}
+
+// tableRows are the row indexes of the rows of a table written at the anchor
+func tableRows(table domain.Table, a anchor) []int {
+	rows := make([]int, 0, len(table.Rows))
+	for i := range table.Rows {
+		rows = append(rows, a.row+1+i)
+	}
+	return rows
+}
@@ -0,0 +1,54 @@
+package sheets
+
+import (
+	"habitsSync/internal/domain"
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+func TestNamedRangeRequests(t *testing.T) {
+	table := domain.HabitsTable([]domain.Habit{{ID: 1, Name: "run"}, {ID: 2, Name: "read"}})
+	existing := []*sheets.NamedRange{
+		{NamedRangeId: "counts", Name: "habits_counts", Range: &sheets.GridRange{SheetId: 42}},
+		{NamedRangeId: "walk", Name: "habit_3_count", Range: &sheets.GridRange{SheetId: 42}},
+		{NamedRangeId: "other", Name: "habit_3_count_2020", Range: &sheets.GridRange{SheetId: 42}},
+		{NamedRangeId: "elsewhere", Name: "habit_4_count", Range: &sheets.GridRange{SheetId: 7}},
+		{NamedRangeId: "weekly", Name: "weekly_count", Range: &sheets.GridRange{SheetId: 42}},
+		{NamedRangeId: "kr", Name: "kr_delta", Range: &sheets.GridRange{SheetId: 42}},
+		// Left by an import with -compare
+		{NamedRangeId: "previous", Name: "habit_1_previous", Range: &sheets.GridRange{SheetId: 42}},
+		{NamedRangeId: "delta", Name: "habit_1_delta", Range: &sheets.GridRange{SheetId: 42}},
+	}
+
+	requests := namedRangeRequests(existing, 42, table, defaultAnchor, []int{5, 2}, 6)
+
+	added := make(map[string]*sheets.GridRange)
+	deleted := make([]string, 0)
+	for _, r := range requests {
+		switch {
+		case r.AddNamedRange != nil:
+			added[r.AddNamedRange.NamedRange.Name] = r.AddNamedRange.NamedRange.Range
+		case r.UpdateNamedRange != nil:
+			if r.UpdateNamedRange.NamedRange.NamedRangeId != "counts" {
+				t.Errorf("namedRangeRequests() updated %v, want counts", r.UpdateNamedRange.NamedRange.NamedRangeId)
+			}
+			if got := r.UpdateNamedRange.NamedRange.Range; got.StartRowIndex != 2 || got.EndRowIndex != 6 {
+				t.Errorf("namedRangeRequests() habits_counts rows = %v-%v, want 2-6", got.StartRowIndex, got.EndRowIndex)
+			}
+		case r.DeleteNamedRange != nil:
+			deleted = append(deleted, r.DeleteNamedRange.NamedRangeId)
+		}
+	}
+
+	if len(added) != 3 {
+		t.Errorf("namedRangeRequests() added %v, want names and a count per habit", added)
+	}
+	if run := added["habit_1_count"]; run == nil || run.StartRowIndex != 5 || run.StartColumnIndex != 2 {
+		t.Errorf("namedRangeRequests() habit_1_count = %+v, want the count at row 5", run)
+	}
+	if want := []string{"walk", "previous", "delta"}; !reflect.DeepEqual(deleted, want) {
+		t.Errorf("namedRangeRequests() deleted %v, want %v", deleted, want)
+	}
+}
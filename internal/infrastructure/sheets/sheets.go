@@ -2,36 +2,34 @@ package sheets
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"habitsSync/internal/domain"
-	"io/ioutil"
+	"habitsSync/internal/infrastructure/auth"
+	"habitsSync/internal/infrastructure/retry"
 	"log"
-	"os"
+	"strings"
 
-	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
-	"google.golang.org/api/drive/v3"
 	"google.golang.org/api/option"
 	"google.golang.org/api/sheets/v4"
 )
 
+var errSheetNotFound = errors.New("not found")
+
 type repository struct {
 	client *sheets.Service
 }
 
-func NewRepository(credentialsPaths, tokenPath string) (*repository, error) {
-	config, err := getConfig(credentialsPaths)
-	if err != nil {
-		return nil, fmt.Errorf("unable to parse client secret file to config: %v", err)
-	}
-	tok, err := tokenFromFile(tokenPath)
+// NewRepository authorizes the client with the context of the command, which
+// also bounds the refreshes of its token
+func NewRepository(ctx context.Context, provider *auth.Provider) (*repository, error) {
+	authorized, err := provider.Client(ctx)
 	if err != nil {
 		return nil, err
 	}
-	client := config.Client(context.Background(), tok)
+	client := retry.Client(authorized, retry.DefaultPolicy)
 
-	srv, err := sheets.NewService(context.Background(), option.WithHTTPClient(client))
+	srv, err := sheets.NewService(ctx, option.WithHTTPClient(client))
 	if err != nil {
 		log.Fatalf("Unable to retrieve Sheets client: %v", err)
 	}
@@ -49,7 +47,7 @@ func (r *repository) SheetExists(id string, name string) (bool, error) {
 	return false, nil
 }
 
-func (r *repository) CreateSheet(id string, name string) error {
+func (r *repository) CreateSheet(ctx context.Context, id string, name string, template domain.Template) error {
 	sheetAlreadyExists := func(s *sheets.Spreadsheet, name string) bool {
 		for _, sh := range s.Sheets {
 			if sh.Properties.Title == name {
@@ -63,6 +61,9 @@ func (r *repository) CreateSheet(id string, name string) error {
 			AddSheet: &sheets.AddSheetRequest{
 				Properties: &sheets.SheetProperties{
 					Title: name,
+					GridProperties: &sheets.GridProperties{
+						FrozenRowCount: headerRow + 1,
+					},
 				},
 			},
 		}
@@ -71,11 +72,11 @@ func (r *repository) CreateSheet(id string, name string) error {
 			Requests: []*sheets.Request{&req},
 		}
 
-		_, err := r.client.Spreadsheets.BatchUpdate(id, rbb).Do()
+		_, err := r.client.Spreadsheets.BatchUpdate(id, rbb).Context(ctx).Do()
 		return err
 	}
 
-	s, err := r.client.Spreadsheets.Get(id).Do()
+	s, err := r.client.Spreadsheets.Get(id).Context(ctx).Do()
 	if err != nil {
 		return err
 	}
@@ -84,47 +85,315 @@ func (r *repository) CreateSheet(id string, name string) error {
 		return nil
 	}
 
+	if template.SheetName != "" {
+		return r.copyTemplate(ctx, id, name, template)
+	}
 	return createSheet(name)
 }
 
-func (r *repository) UpdateSheet(id string, name string, stats []domain.Habit) error {
-	rows := make([][]interface{}, 0)
-	rows = append(rows, []interface{}{"ID", "Name", "Count"})
-	for _, st := range stats {
-		rows = append(rows, []interface{}{st.ID, st.Name, st.Count})
+// copyTemplate adds the Sheet as a copy of the template, remembering where its
+// placeholders are
+func (r *repository) copyTemplate(ctx context.Context, id string, name string, template domain.Template) error {
+	source := template.Spreadsheet
+	if source == "" {
+		source = id
 	}
 
-	rb := &sheets.BatchUpdateValuesRequest{
-		ValueInputOption: "USER_ENTERED",
+	sheet, err := r.sheet(ctx, source, template.SheetName)
+	if err != nil {
+		return fmt.Errorf("template: %w", err)
+	}
+	rsp, err := r.client.Spreadsheets.Values.Get(source, quoteSheetName(template.SheetName)).
+		ValueRenderOption("UNFORMATTED_VALUE").
+		Context(ctx).
+		Do()
+	if err != nil {
+		return err
+	}
+	placeholders, ok := findPlaceholders(rsp.Values)
+	if !ok {
+		return fmt.Errorf("template %v has no %v cell", template.SheetName, domain.TableAnchor)
+	}
+
+	if source == id {
+		// The ID of the copy is chosen here, so the copy and its metadata are
+		// added at once: a copy without them would be cleared as a plain Sheet
+		sheetID, err := newObjectID()
+		if err != nil {
+			return err
+		}
+		metadata, err := templateMetadataRequest(sheetID, placeholders)
+		if err != nil {
+			return err
+		}
+		rb := &sheets.BatchUpdateSpreadsheetRequest{Requests: []*sheets.Request{{
+			DuplicateSheet: &sheets.DuplicateSheetRequest{
+				SourceSheetId: sheet.Properties.SheetId,
+				NewSheetId:    sheetID,
+				NewSheetName:  name,
+			},
+		}, metadata}}
+		_, err = r.client.Spreadsheets.BatchUpdate(id, rb).Context(ctx).Do()
+		return err
 	}
-	rb.Data = append(rb.Data, &sheets.ValueRange{
-		Range:  fmt.Sprintf("%v!A2", name),
-		Values: rows,
-	})
 
-	_, err := r.client.Spreadsheets.Values.BatchUpdate(id, rb).Do()
+	// Copies are named "Copy of ..." on the destination
+	copied, err := r.client.Spreadsheets.Sheets.CopyTo(source, sheet.Properties.SheetId,
+		&sheets.CopySheetToAnotherSpreadsheetRequest{DestinationSpreadsheetId: id}).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	metadata, err := templateMetadataRequest(copied.SheetId, placeholders)
+	if err == nil {
+		rb := &sheets.BatchUpdateSpreadsheetRequest{Requests: []*sheets.Request{{
+			UpdateSheetProperties: &sheets.UpdateSheetPropertiesRequest{
+				Properties: &sheets.SheetProperties{SheetId: copied.SheetId, Title: name},
+				Fields:     "title",
+			},
+		}, metadata}}
+		_, err = r.client.Spreadsheets.BatchUpdate(id, rb).Context(ctx).Do()
+	}
 	if err != nil {
+		// A copy without metadata would be cleared as a plain Sheet
+		rb := &sheets.BatchUpdateSpreadsheetRequest{Requests: []*sheets.Request{{
+			DeleteSheet: &sheets.DeleteSheetRequest{SheetId: copied.SheetId},
+		}}}
+		if _, deleteErr := r.client.Spreadsheets.BatchUpdate(id, rb).Context(ctx).Do(); deleteErr != nil {
+			return fmt.Errorf("%w, and the copy of the template could not be deleted: %v", err, deleteErr)
+		}
 		return err
 	}
 	return nil
 }
 
-func getConfig(credentialsPath string) (*oauth2.Config, error) {
-	b, err := ioutil.ReadFile(credentialsPath)
+// UpdateSheet replaces the content of the Sheet with the table. The Sheet is
+// cleared in the same batch, so rows of a previous and longer import do not
+// remain at the bottom. Sheets made from a template are filled instead
+func (r *repository) UpdateSheet(ctx context.Context, id string, name string, table domain.Table) error {
+	sheet, err := r.sheet(ctx, id, name)
+	if err != nil {
+		return err
+	}
+	sheetID := sheet.Properties.SheetId
+
+	template, err := templateOf(sheet)
+	if err != nil {
+		return err
+	}
+
+	a := defaultAnchor
+	var requests []*sheets.Request
+	if template != nil {
+		a = template.anchor()
+		if requests, err = fillTemplate(sheet, table, *template); err != nil {
+			return err
+		}
+	} else {
+		requests = overwriteRequests(sheet, table)
+	}
+	end := a.row + 1 + len(table.Rows)
+	decorated, err := decorations(sheet, table, a, end)
+	if err != nil {
+		return err
+	}
+	requests = append(requests, decorated...)
+
+	ranges, err := r.namedRanges(ctx, id, table)
+	if err != nil {
+		return err
+	}
+	requests = append(requests, namedRangeRequests(ranges, sheetID, table, a, tableRows(table, a), end)...)
+
+	rb := &sheets.BatchUpdateSpreadsheetRequest{Requests: requests}
+
+	_, err = r.client.Spreadsheets.BatchUpdate(id, rb).Context(ctx).Do()
+	return err
+}
+
+// overwriteRequests clear the Sheet and write the table below its title
+func overwriteRequests(sheet *sheets.Sheet, table domain.Table) []*sheets.Request {
+	sheetID := sheet.Properties.SheetId
+
+	rows := rowsData(table)
+	requests := []*sheets.Request{clearSheet(sheetID)}
+	if grow := growGrid(sheet.Properties, headerRow+len(rows), len(table.Columns)); grow != nil {
+		requests = append(requests, grow)
+	}
+	requests = append(requests, &sheets.Request{
+		UpdateCells: &sheets.UpdateCellsRequest{
+			Start:  &sheets.GridCoordinate{SheetId: sheetID, RowIndex: headerRow},
+			Rows:   rows,
+			Fields: cellFields,
+		},
+	})
+	requests = append(requests, formatRequests(sheetID, table, defaultAnchor)...)
+	return append(requests, autoResize(sheetID, len(table.Columns)), writeTitle(sheetID, table.Title))
+}
+
+// MergeSheet writes the table on the Sheet keeping the columns of the user.
+// Rows are matched by the key of the table, see mergeRequests
+func (r *repository) MergeSheet(ctx context.Context, id string, name string, table domain.Table) error {
+	sheet, err := r.sheet(ctx, id, name)
+	if err != nil {
+		return err
+	}
+	template, err := templateOf(sheet)
+	if err != nil {
+		return err
+	}
+	if template != nil {
+		return fmt.Errorf("sheet %v was made from a template, it can only be overwritten", name)
+	}
+
+	existing, err := r.values(ctx, id, name)
+	if err != nil {
+		return err
+	}
+
+	sheetID := sheet.Properties.SheetId
+
+	requests, a, positions, rows := mergeRequests(sheetID, existing, table)
+	width := a.width(len(table.Columns))
+	if grow := growGrid(sheet.Properties, rows, width); grow != nil {
+		requests = append([]*sheets.Request{grow}, requests...)
+	}
+	requests = append([]*sheets.Request{writeTitle(sheetID, "")}, requests...)
+	requests = append(requests, formatRequests(sheetID, table, a)...)
+	requests = append(requests, autoResize(sheetID, width), writeTitle(sheetID, table.Title))
+	decorated, err := decorations(sheet, table, a, rows)
+	if err != nil {
+		return err
+	}
+	requests = append(requests, decorated...)
+
+	ranges, err := r.namedRanges(ctx, id, table)
+	if err != nil {
+		return err
+	}
+	requests = append(requests, namedRangeRequests(ranges, sheetID, table, a, positions, rows)...)
+
+	rb := &sheets.BatchUpdateSpreadsheetRequest{Requests: requests}
+	_, err = r.client.Spreadsheets.BatchUpdate(id, rb).Context(ctx).Do()
+	return err
+}
+
+// AppendSheet adds the rows of the table after the last row of the Sheet,
+// skipping the ones whose key is already on the Sheet. The header is written
+// when the Sheet is empty. Values are appended as typed by a user, so the
+// header and the columns are formatted afterwards
+func (r *repository) AppendSheet(ctx context.Context, id string, name string, table domain.Table) error {
+	sheet, err := r.sheet(ctx, id, name)
+	if err != nil {
+		return err
+	}
+	existing, err := r.values(ctx, id, name)
+	if err != nil {
+		return err
+	}
+
+	rows := appendRows(existing, table)
+	if len(rows) == 0 {
+		return nil
+	}
+
+	_, err = r.client.Spreadsheets.Values.Append(id, quoteSheetName(name)+"!A2", &sheets.ValueRange{Values: rows}).
+		ValueInputOption("USER_ENTERED").
+		InsertDataOption("INSERT_ROWS").
+		Context(ctx).
+		Do()
+	if err != nil {
+		return err
+	}
+
+	rb := &sheets.BatchUpdateSpreadsheetRequest{Requests: formatRequests(sheet.Properties.SheetId, table, defaultAnchor)}
+	_, err = r.client.Spreadsheets.BatchUpdate(id, rb).Context(ctx).Do()
+	return err
+}
+
+// ReadSheet returns the table written on the Sheet, at the anchor of its
+// template if it has one and as wide and long as its last import. Dates are
+// read as shown
+func (r *repository) ReadSheet(ctx context.Context, id string, name string) (domain.Table, error) {
+	sheet, err := r.sheet(ctx, id, name)
+	if errors.Is(err, errSheetNotFound) {
+		return domain.Table{}, nil
+	}
+	if err != nil {
+		return domain.Table{}, err
+	}
+
+	a := defaultAnchor
+	template, err := templateOf(sheet)
+	if err != nil {
+		return domain.Table{}, err
+	}
+	if template != nil {
+		a = template.anchor()
+	}
+
+	rsp, err := r.client.Spreadsheets.Values.Get(id, quoteSheetName(name)).
+		ValueRenderOption("UNFORMATTED_VALUE").
+		DateTimeRenderOption("FORMATTED_STRING").
+		Context(ctx).
+		Do()
+	if err != nil {
+		return domain.Table{}, err
+	}
+	t := readTable(rsp.Values, a)
+	if template != nil {
+		// Filling a template replaces only the table of the previous import
+		t = template.table(t)
+	}
+	return t, nil
+}
+
+// values returns the unformatted values of the Sheet from the header row on
+func (r *repository) values(ctx context.Context, id string, name string) ([][]interface{}, error) {
+	rsp, err := r.client.Spreadsheets.Values.Get(id, quoteSheetName(name)).
+		ValueRenderOption("UNFORMATTED_VALUE").
+		Context(ctx).
+		Do()
+	if err != nil {
+		return nil, err
+	}
+	if len(rsp.Values) <= headerRow {
+		return make([][]interface{}, 0), nil
+	}
+	return rsp.Values[headerRow:], nil
+}
+
+// sheet returns the properties of the Sheet, with the ranges of its
+// conditional formatting, the IDs of its charts and its developer metadata
+func (r *repository) sheet(ctx context.Context, id string, name string) (*sheets.Sheet, error) {
+	s, err := r.client.Spreadsheets.Get(id).
+		Fields("sheets(properties,conditionalFormats(ranges),charts(chartId),developerMetadata(metadataId,metadataKey,metadataValue))").
+		Context(ctx).
+		Do()
 	if err != nil {
 		return nil, err
 	}
-	return google.ConfigFromJSON(b, drive.DriveMetadataReadonlyScope, drive.DriveReadonlyScope)
+	for _, sh := range s.Sheets {
+		if sh.Properties.Title == name {
+			return sh, nil
+		}
+	}
+	return nil, fmt.Errorf("sheet %v %w", name, errSheetNotFound)
 }
 
-// Retrieves a token from a local file.
-func tokenFromFile(file string) (*oauth2.Token, error) {
-	f, err := os.Open(file)
+// namedRanges returns the named ranges of the spreadsheet, only when the
+// table has any to be kept
+func (r *repository) namedRanges(ctx context.Context, id string, table domain.Table) ([]*sheets.NamedRange, error) {
+	if len(table.NamedRanges()) == 0 {
+		return nil, nil
+	}
+	s, err := r.client.Spreadsheets.Get(id).Fields("namedRanges").Context(ctx).Do()
 	if err != nil {
 		return nil, err
 	}
-	defer func() { _ = f.Close() }()
-	tok := &oauth2.Token{}
-	err = json.NewDecoder(f).Decode(tok)
-	return tok, err
+	return s.NamedRanges, nil
+}
+
+// quoteSheetName allows using sheet names with spaces or quotes on A1 notation
+func quoteSheetName(name string) string {
+	return "'" + strings.ReplaceAll(name, "'", "''") + "'"
 }
@@ -0,0 +1,177 @@
+package sheets
+
+import (
+	"encoding/json"
+	"fmt"
+	"habitsSync/internal/domain"
+	"strings"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// templateKey is the developer metadata of the Sheets made from a template.
+// The placeholders are replaced on the first import, so their place must be
+// remembered for the next ones
+const templateKey = "habitsSync.template"
+
+type templateMetadata struct {
+	Row    int               `json:"row"`
+	Column int               `json:"column"`
+	Cells  []placeholderCell `json:"cells,omitempty"`
+	// Rows and Columns are the size of the table written by the last import,
+	// header included. Zero before the first one, when only the anchor is there
+	Rows    int `json:"rows,omitempty"`
+	Columns int `json:"columns,omitempty"`
+
+	// id is the one of the developer metadata, to update it
+	id int64
+}
+
+// placeholderCell is a cell of the template with {{placeholders}} on its text
+type placeholderCell struct {
+	Row    int    `json:"row"`
+	Column int    `json:"column"`
+	Text   string `json:"text"`
+}
+
+func (t templateMetadata) anchor() anchor {
+	return anchor{row: t.Row, column: t.Column}
+}
+
+// table cuts the table read at the anchor to the size of the last import
+func (t templateMetadata) table(read domain.Table) domain.Table {
+	if len(read.Columns) > t.Columns {
+		read.Columns = read.Columns[:t.Columns]
+	}
+	if rows := max(t.Rows-1, 0); len(read.Rows) > rows {
+		read.Rows = read.Rows[:rows]
+	}
+	for i, row := range read.Rows {
+		if len(row) > t.Columns {
+			read.Rows[i] = row[:t.Columns]
+		}
+	}
+	return read
+}
+
+// findPlaceholders looks for the table anchor and the rest of placeholders on
+// the values of a template. Returns false if there is no table anchor
+func findPlaceholders(values [][]interface{}) (templateMetadata, bool) {
+	t := templateMetadata{}
+	found := false
+	for i, row := range values {
+		for j, v := range row {
+			text, ok := v.(string)
+			if !ok || !strings.Contains(text, "{{") {
+				continue
+			}
+			if strings.TrimSpace(text) == domain.TableAnchor {
+				t.Row, t.Column, found = i, j, true
+				continue
+			}
+			t.Cells = append(t.Cells, placeholderCell{Row: i, Column: j, Text: text})
+		}
+	}
+	return t, found
+}
+
+// templateOf returns the placeholders of a Sheet made from a template, or nil
+// for the rest of Sheets
+func templateOf(sheet *sheets.Sheet) (*templateMetadata, error) {
+	for _, m := range sheet.DeveloperMetadata {
+		if m.MetadataKey != templateKey {
+			continue
+		}
+		t := &templateMetadata{id: m.MetadataId}
+		if err := json.Unmarshal([]byte(m.MetadataValue), t); err != nil {
+			return nil, fmt.Errorf("invalid template metadata on %v: %w", sheet.Properties.Title, err)
+		}
+		return t, nil
+	}
+	return nil, nil
+}
+
+func templateMetadataRequest(sheetID int64, t templateMetadata) (*sheets.Request, error) {
+	value, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	return sheetMetadataRequest(sheetID, templateKey, string(value)), nil
+}
+
+// sheetMetadataRequest adds developer metadata to the Sheet
+func sheetMetadataRequest(sheetID int64, key string, value string) *sheets.Request {
+	return &sheets.Request{
+		CreateDeveloperMetadata: &sheets.CreateDeveloperMetadataRequest{
+			DeveloperMetadata: &sheets.DeveloperMetadata{
+				MetadataKey:   key,
+				MetadataValue: value,
+				Visibility:    "DOCUMENT",
+				Location: &sheets.DeveloperMetadataLocation{
+					SheetId:         sheetID,
+					ForceSendFields: []string{"SheetId"},
+				},
+			},
+		},
+	}
+}
+
+// fillTemplate writes the table at the anchor of the template, clearing only
+// the table written by the previous import, and renders the placeholders.
+// Anything else of the template, like totals below the table, is kept
+func fillTemplate(sheet *sheets.Sheet, table domain.Table, t templateMetadata) ([]*sheets.Request, error) {
+	sheetID := sheet.Properties.SheetId
+	a := t.anchor()
+	rows := rowsData(table)
+
+	requests := make([]*sheets.Request, 0)
+	if grow := growGrid(sheet.Properties, a.row+len(rows), a.column+len(table.Columns)); grow != nil {
+		requests = append(requests, grow)
+	}
+	requests = append(requests,
+		&sheets.Request{
+			UpdateCells: &sheets.UpdateCellsRequest{
+				Range: &sheets.GridRange{
+					SheetId:          sheetID,
+					StartRowIndex:    int64(a.row),
+					EndRowIndex:      int64(a.row + max(t.Rows, 1)),
+					StartColumnIndex: int64(a.column),
+					EndColumnIndex:   int64(a.column + max(t.Columns, 1)),
+				},
+				Fields: cellFields,
+			},
+		},
+		&sheets.Request{
+			UpdateCells: &sheets.UpdateCellsRequest{
+				Start:  &sheets.GridCoordinate{SheetId: sheetID, RowIndex: int64(a.row), ColumnIndex: int64(a.column)},
+				Rows:   rows,
+				Fields: cellFields,
+			},
+		},
+	)
+	for _, c := range t.Cells {
+		requests = append(requests, &sheets.Request{
+			UpdateCells: &sheets.UpdateCellsRequest{
+				Start:  &sheets.GridCoordinate{SheetId: sheetID, RowIndex: int64(c.Row), ColumnIndex: int64(c.Column)},
+				Rows:   []*sheets.RowData{rowData([]interface{}{table.Render(c.Text)}, nil)},
+				Fields: "userEnteredValue",
+			},
+		})
+	}
+
+	t.Rows, t.Columns = len(rows), len(table.Columns)
+	value, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	requests = append(requests, &sheets.Request{
+		UpdateDeveloperMetadata: &sheets.UpdateDeveloperMetadataRequest{
+			DataFilters: []*sheets.DataFilter{{
+				DeveloperMetadataLookup: &sheets.DeveloperMetadataLookup{MetadataId: t.id},
+			}},
+			DeveloperMetadata: &sheets.DeveloperMetadata{MetadataValue: string(value)},
+			Fields:            "metadataValue",
+		},
+	})
+	return requests, nil
+}
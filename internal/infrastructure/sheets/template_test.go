@@ -0,0 +1,123 @@
+package sheets
+
+import (
+	"habitsSync/internal/domain"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+func TestFindPlaceholders(t *testing.T) {
+	values := [][]interface{}{
+		{"Habits from {{from}} to {{to}}", "", 3.0},
+		{},
+		{"", "", " {{table}} ", "=SUM(habits_counts)"},
+	}
+
+	got, ok := findPlaceholders(values)
+	if !ok {
+		t.Fatal("findPlaceholders() did not find the table")
+	}
+	want := templateMetadata{
+		Row: 2, Column: 2,
+		Cells: []placeholderCell{{Row: 0, Column: 0, Text: "Habits from {{from}} to {{to}}"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findPlaceholders() = %+v, want %+v", got, want)
+	}
+
+	if _, ok := findPlaceholders(values[:1]); ok {
+		t.Error("findPlaceholders() found a table on a template without it")
+	}
+}
+
+func TestFillTemplate(t *testing.T) {
+	placeholders := templateMetadata{
+		Row: 2, Column: 2,
+		Cells: []placeholderCell{{Row: 0, Column: 0, Text: "Habits from {{from}} to {{to}}"}},
+	}
+	metadata, err := templateMetadataRequest(42, placeholders)
+	if err != nil {
+		t.Fatalf("templateMetadataRequest() error = %v", err)
+	}
+	sheet := &sheets.Sheet{
+		Properties:        &sheets.SheetProperties{SheetId: 42, Title: "Q1"},
+		DeveloperMetadata: []*sheets.DeveloperMetadata{metadata.CreateDeveloperMetadata.DeveloperMetadata},
+	}
+
+	template, err := templateOf(sheet)
+	if err != nil || template == nil {
+		t.Fatalf("templateOf() = %v, %v, want the placeholders", template, err)
+	}
+	if !reflect.DeepEqual(*template, placeholders) {
+		t.Errorf("templateOf() = %+v, want %+v", *template, placeholders)
+	}
+
+	table := domain.HabitsTable([]domain.Habit{{ID: 1, Name: "run", Count: 3, Color: -1}})
+	table.Placeholders = map[string]string{"from": "2021-01-01", "to": "2021-03-31"}
+	requests, err := fillTemplate(sheet, table, *template)
+	if err != nil {
+		t.Fatalf("fillTemplate() error = %v", err)
+	}
+
+	clear := requests[0].UpdateCells.Range
+	if clear.StartRowIndex != 2 || clear.EndRowIndex != 3 || clear.StartColumnIndex != 2 || clear.EndColumnIndex != 3 {
+		t.Errorf("fillTemplate() cleared %+v, want only the anchor on the first import", clear)
+	}
+	start := requests[1].UpdateCells.Start
+	if start.RowIndex != 2 || start.ColumnIndex != 2 || len(requests[1].UpdateCells.Rows) != 2 {
+		t.Errorf("fillTemplate() wrote the table at %+v, want the anchor", start)
+	}
+	title := requests[2].UpdateCells.Rows[0].Values[0].UserEnteredValue.StringValue
+	if title == nil || *title != "Habits from 2021-01-01 to 2021-03-31" {
+		t.Errorf("fillTemplate() title = %v, want the placeholders rendered", title)
+	}
+	update := requests[3].UpdateDeveloperMetadata
+	if update == nil || !strings.Contains(update.DeveloperMetadata.MetadataValue, `"rows":2,"columns":5`) {
+		t.Errorf("fillTemplate() did not remember the size of the table: %+v", update)
+	}
+
+	// The next import clears the table written by this one, and nothing below
+	template.Rows, template.Columns = 4, 7
+	requests, err = fillTemplate(sheet, table, *template)
+	if err != nil {
+		t.Fatalf("fillTemplate() error = %v", err)
+	}
+	clear = requests[0].UpdateCells.Range
+	if clear.StartRowIndex != 2 || clear.EndRowIndex != 6 || clear.StartColumnIndex != 2 || clear.EndColumnIndex != 9 {
+		t.Errorf("fillTemplate() cleared %+v, want the previous table", clear)
+	}
+}
+
+func TestTemplateOf_notTemplate(t *testing.T) {
+	sheet := &sheets.Sheet{
+		Properties:        &sheets.SheetProperties{SheetId: 42},
+		DeveloperMetadata: []*sheets.DeveloperMetadata{{MetadataKey: "other", MetadataValue: "x"}},
+	}
+	if template, err := templateOf(sheet); template != nil || err != nil {
+		t.Errorf("templateOf() = %v, %v, want nil", template, err)
+	}
+}
+
+func TestTemplateMetadata_table(t *testing.T) {
+	values := [][]interface{}{
+		{"Habits"},
+		{"ID", "Name", "", "Notes"},
+		{1.0, "run", "", "mine"},
+		{},
+		{"", "Total", "", 1.0},
+	}
+	template := templateMetadata{Row: 1, Rows: 2, Columns: 2}
+
+	got := template.table(readTable(values, template.anchor()))
+
+	want := domain.Table{
+		Columns: []domain.Column{{Name: "ID"}, {Name: "Name"}},
+		Rows:    [][]interface{}{{1.0, "run"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("table() = %+v, want %+v", got, want)
+	}
+}